@@ -0,0 +1,68 @@
+package markdump
+
+import (
+	"regexp"
+	"strings"
+)
+
+// matches the rendered start of a GitHub-style alert like "> [!NOTE]"
+var admonitionStart = regexp.MustCompile(`<blockquote>\n<p>\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\](?:\n|</p>\n)`)
+
+// admonitionClasses maps the alert types to bootstrap alert classes.
+var admonitionClasses = map[string]string{
+	"NOTE":      "alert-info",
+	"TIP":       "alert-success",
+	"IMPORTANT": "alert-primary",
+	"WARNING":   "alert-warning",
+	"CAUTION":   "alert-danger",
+}
+
+// renderAdmonitions replaces blockquotes which start with a GitHub-style alert marker by styled admonition blocks. Other blockquotes are kept.
+func renderAdmonitions(html string) string {
+	var sb strings.Builder
+	for {
+		loc := admonitionStart.FindStringSubmatchIndex(html)
+		if loc == nil {
+			break
+		}
+		end := closingBlockquote(html, loc[1])
+		if end < 0 {
+			break
+		}
+		kind := html[loc[2]:loc[3]]
+		sb.WriteString(html[:loc[0]])
+		sb.WriteString(`<div class="alert admonition admonition-` + strings.ToLower(kind) + ` ` + admonitionClasses[kind] + `" role="note">`)
+		sb.WriteString(`<p class="admonition-title">` + kind[:1] + strings.ToLower(kind[1:]) + "</p>\n")
+		if !strings.HasSuffix(html[loc[0]:loc[1]], "</p>\n") {
+			sb.WriteString("<p>") // the marker shares the paragraph with the content
+		}
+		sb.WriteString(renderAdmonitions(html[loc[1]:end]))
+		sb.WriteString("</div>")
+		html = html[end+len("</blockquote>"):]
+	}
+	sb.WriteString(html)
+	return sb.String()
+}
+
+// closingBlockquote returns the index of the </blockquote> tag which closes a blockquote whose content starts at from, or -1.
+func closingBlockquote(html string, from int) int {
+	depth := 1
+	for i := from; i < len(html); {
+		open := strings.Index(html[i:], "<blockquote>")
+		close := strings.Index(html[i:], "</blockquote>")
+		switch {
+		case close < 0:
+			return -1
+		case open >= 0 && open < close:
+			depth++
+			i += open + len("<blockquote>")
+		default:
+			depth--
+			if depth == 0 {
+				return i + close
+			}
+			i += close + len("</blockquote>")
+		}
+	}
+	return -1
+}
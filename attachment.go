@@ -0,0 +1,78 @@
+package markdump
+
+import (
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Attachment is a file which is not displayed as a page, like a PDF or an image. It is listed if Server.ListAttachments is set, and served as is.
+type Attachment struct {
+	name string
+	Size int64  // bytes
+	Type string // MIME type, empty if unknown
+	url  string
+}
+
+func newAttachment(dirURL, name string, size int64) *Attachment {
+	typ, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(name)), ";")
+	return &Attachment{
+		name: name,
+		Size: size,
+		Type: typ,
+		url:  strings.TrimSuffix(dirURL, "/") + "/" + url.PathEscape(name), // the file name, not a slug
+	}
+}
+
+func (attachment *Attachment) IsAttachment() bool {
+	return true
+}
+
+func (attachment *Attachment) IsDir() bool {
+	return false
+}
+
+func (attachment *Attachment) Title() string {
+	return attachment.name
+}
+
+func (attachment *Attachment) URL() string {
+	return attachment.url
+}
+
+// HumanSize returns the size in B, KB, MB or GB.
+func (attachment *Attachment) HumanSize() string {
+	size := float64(attachment.Size)
+	for _, unit := range []string{"B", "KB", "MB"} {
+		if size < 1000 {
+			if unit == "B" {
+				return fmt.Sprintf("%d B", attachment.Size)
+			}
+			return fmt.Sprintf("%.1f %s", size, unit)
+		}
+		size /= 1000
+	}
+	return fmt.Sprintf("%.1f GB", size)
+}
+
+// foldedName returns the name of the only regular, non-hidden file in the dir of fsPath whose name equals the base of fsPath under Unicode case folding. It returns false if there is none or more than one.
+func foldedName(fsPath string) (string, bool) {
+	entries, err := os.ReadDir(filepath.Dir(fsPath))
+	if err != nil {
+		return "", false
+	}
+	var found string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || !strings.EqualFold(entry.Name(), filepath.Base(fsPath)) {
+			continue
+		}
+		if found != "" {
+			return "", false // ambiguous
+		}
+		found = entry.Name()
+	}
+	return found, found != ""
+}
@@ -0,0 +1,137 @@
+package markdump
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentSize(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/readme.md":  "Documents",
+		"docs/report.pdf": strings.Repeat("x", 2500),
+	}, func(srv *Server) {
+		srv.ListAttachments = true
+	})
+	body := serve(srv, "/docs").Body.String()
+	if !strings.Contains(body, "report.pdf</a> <small class=\"text-body-secondary\">2.5 KB, application/pdf</small>") {
+		t.Fatalf("listing does not contain the PDF with its size and type: %s", body)
+	}
+
+	for _, test := range []struct {
+		size int64
+		want string
+	}{
+		{999, "999 B"},
+		{1500, "1.5 KB"},
+		{2_500_000, "2.5 MB"},
+		{3_000_000_000, "3.0 GB"},
+	} {
+		if got := (&Attachment{Size: test.size}).HumanSize(); got != test.want {
+			t.Errorf("HumanSize(%d): got %q, want %q", test.size, got, test.want)
+		}
+	}
+}
+
+func TestAttachmentListed(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/guide.md":      "Guide",
+		"docs/Slides v2.odp": "slides",
+		"docs/.hidden.txt":   "hidden",
+	}, func(srv *Server) {
+		srv.ListAttachments = true
+	})
+	body := serve(srv, "/docs").Body.String()
+	if !strings.Contains(body, `<a href="/docs/Slides%20v2.odp" download>Slides v2.odp</a>`) {
+		t.Fatalf("attachment is not listed with a download link: %s", body)
+	}
+	if strings.Contains(body, ".hidden.txt") {
+		t.Errorf("hidden file is listed")
+	}
+
+	w := serve(srv, "/docs/Slides%20v2.odp")
+	if w.Body.String() != "slides" {
+		t.Fatalf("got %q, want the raw file", w.Body.String())
+	}
+	if hrefs := searchHrefs(t, srv, "slides"); len(hrefs) > 0 {
+		t.Errorf("attachment is indexed as a page: %v", hrefs)
+	}
+}
+
+func TestAttachmentEscapedURL(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/guide.md":      "Guide",
+		"docs/Q&A #1?.txt":   "questions",
+		"docs/100% done.txt": "done",
+	}, func(srv *Server) {
+		srv.ListAttachments = true
+	})
+	body := serve(srv, "/docs").Body.String()
+	for _, test := range []struct {
+		href    string
+		content string
+	}{
+		{"/docs/Q&A%20%231%3F.txt", "questions"},
+		{"/docs/100%25%20done.txt", "done"},
+	} {
+		if !strings.Contains(body, `<a href="`+strings.ReplaceAll(test.href, "&", "&amp;")+`" download>`) {
+			t.Errorf("listing does not link %s: %s", test.href, body)
+		}
+		if got := serve(srv, test.href).Body.String(); got != test.content {
+			t.Errorf("GET %s: got %q, want %q", test.href, got, test.content)
+		}
+	}
+}
+
+func TestAttachmentOnlyDir(t *testing.T) {
+	files := map[string]string{
+		"page.md":         "Hello",
+		"scans/page1.pdf": "pdf",
+		"empty/.keep":     "",
+	}
+	for _, test := range []struct {
+		listAttachments bool
+		keepEmptyDirs   bool
+		scans, empty    bool
+	}{
+		{false, false, false, false},
+		{true, false, true, false},
+		{false, true, true, true},
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.ListAttachments = test.listAttachments
+			srv.KeepEmptyDirs = test.keepEmptyDirs
+		})
+		subdirs := srv.current.Load().root.Subdirs
+		if _, ok := subdirs["scans"]; ok != test.scans {
+			t.Errorf("ListAttachments %t, KeepEmptyDirs %t: got attachment-only dir %t, want %t", test.listAttachments, test.keepEmptyDirs, ok, test.scans)
+		}
+		if _, ok := subdirs["empty"]; ok != test.empty {
+			t.Errorf("ListAttachments %t, KeepEmptyDirs %t: got empty dir %t, want %t", test.listAttachments, test.keepEmptyDirs, ok, test.empty)
+		}
+	}
+}
+
+func TestFoldAttachmentCase(t *testing.T) {
+	files := map[string]string{
+		"docs/page.md":   "![](Image.PNG)",
+		"docs/image.png": "png",
+		"docs/photo.jpg": "one",
+		"docs/Photo.JPG": "two",
+	}
+	srv := newTestServer(t, files, func(srv *Server) {
+		srv.FoldAttachmentCase = true
+	})
+	w := serve(srv, "/docs/Image.PNG?v=1")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/docs/image.png?v=1" {
+		t.Fatalf("got status %d and location %q, want a redirect to /docs/image.png?v=1", w.Code, w.Header().Get("Location"))
+	}
+	if code := serve(srv, "/docs/PHOTO.jpg").Code; code != http.StatusNotFound {
+		t.Errorf("ambiguous name: got status %d, want %d", code, http.StatusNotFound)
+	}
+
+	srv = newTestServer(t, files, nil)
+	if code := serve(srv, "/docs/Image.PNG").Code; code != http.StatusNotFound {
+		t.Errorf("without FoldAttachmentCase: got status %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,70 @@
+package markdump
+
+import (
+	"html/template"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// authOnlyRegion matches a region which is displayed to authenticated users only. The comments must be on lines of their own, so the markdown renderer keeps them as HTML blocks.
+var authOnlyRegion = regexp.MustCompile(`(?s)<!--\s*auth-only\s*-->.*?<!--\s*/auth-only\s*-->`)
+
+// authOnlyMarker matches the start or end comment of an auth-only region.
+var authOnlyMarker = regexp.MustCompile(`^<!--\s*/?auth-only\s*-->$`)
+
+// stripAuthOnly removes auth-only regions from markdown or HTML content.
+func stripAuthOnly(content []byte) []byte {
+	return authOnlyRegion.ReplaceAll(content, nil)
+}
+
+// withAuthOnly reports whether the raw file with the given name may contain auth-only regions. This applies to markdown files, including special ones like _footer.md which are not served as pages, and to HTML pages.
+func (srv *Server) withAuthOnly(name string) bool {
+	return strings.HasSuffix(name, ".md") || srv.ServeHTML && strings.HasSuffix(name, ".html")
+}
+
+// anonymous reports whether auth-only regions must be hidden from the request. This is the case if the request carries no valid token other than "public". On private servers, this affects the login and error pages of unauthenticated requests only.
+func (srv *Server) anonymous(r *http.Request) bool {
+	token := r.URL.Query().Get("auth")
+	if token == "" {
+		if cookie, err := r.Cookie("auth"); err == nil {
+			token = cookie.Value
+		}
+	}
+	return token == "" || token == "public" || !slices.Contains(srv.authTokens(), token)
+}
+
+// HTML returns the HTML content of file in the language of the request, without auth-only regions for anonymous requests.
+func (data layoutData) HTML(file *File) template.HTML {
+	if data.Anonymous {
+		return localize(data.Lang, template.HTML(stripAuthOnly([]byte(file.HTMLContent))))
+	}
+	return localize(data.Lang, file.HTMLContent)
+}
+
+// authTokens returns the valid authentication tokens, which are srv.AuthTokens until the first Reload has read srv.AuthTokenFile.
+func (srv *Server) authTokens() []string {
+	if st := srv.current.Load(); st != nil {
+		return st.authTokens
+	}
+	return srv.AuthTokens
+}
+
+// readTokenFile returns the tokens in the given file, one per line. Empty lines and lines starting with # are skipped.
+func readTokenFile(name string) ([]string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,127 @@
+package markdump
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthOnly(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Public text.\n\n<!-- auth-only -->\nInternal phone number.\n<!-- /auth-only -->\n\nMore public text.",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"public", "staff"}
+	})
+	for _, test := range []struct {
+		target   string
+		internal bool
+	}{
+		{"/page", false},
+		{"/page?auth=wrong", false},
+		{"/page?auth=staff", true},
+		{"/page?auth=staff&source=1", true},
+		{"/page?source=1", false},
+	} {
+		body := serve(srv, test.target).Body.String()
+		if !strings.Contains(body, "More public text.") {
+			t.Errorf("GET %s: public text is missing", test.target)
+		}
+		if internal := strings.Contains(body, "Internal phone number."); internal != test.internal {
+			t.Errorf("GET %s: got internal text %t, want %t", test.target, internal, test.internal)
+		}
+	}
+	if hrefs := searchHrefs(t, srv, "phone"); len(hrefs) > 0 {
+		t.Errorf("auth-only text is searchable: %v", hrefs)
+	}
+}
+
+func TestAuthOnlyRawFiles(t *testing.T) {
+	const authOnly = "Public text.\n\n<!-- auth-only -->\nInternal phone number.\n<!-- /auth-only -->\n\nMore public text."
+	srv := newTestServer(t, map[string]string{
+		"page.md":    authOnly,
+		"_footer.md": authOnly,
+		"404.md":     authOnly,
+		"other.html": authOnly,
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"public", "staff"}
+		srv.ServeHTML = true
+	})
+	for _, target := range []string{"/page.md", "/_footer.md", "/404.md", "/other.html"} {
+		for _, test := range []struct {
+			query    string
+			internal bool
+		}{
+			{"", false},
+			{"?auth=staff", true},
+		} {
+			w := serve(srv, target+test.query)
+			if w.Code != http.StatusOK {
+				t.Errorf("GET %s%s: got status %d", target, test.query, w.Code)
+				continue
+			}
+			body := w.Body.String()
+			if !strings.Contains(body, "More public text.") {
+				t.Errorf("GET %s%s: public text is missing", target, test.query)
+			}
+			if internal := strings.Contains(body, "Internal phone number."); internal != test.internal {
+				t.Errorf("GET %s%s: got internal text %t, want %t", target, test.query, internal, test.internal)
+			}
+		}
+	}
+
+	body := serve(srv, "/?download=zip").Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), "Internal phone number.") {
+			t.Errorf("zip: %s contains internal text", f.Name)
+		}
+	}
+}
+
+func TestAuthTokenFile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(tokenFile, []byte("# rotated weekly\nfirst\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"env"}
+		srv.AuthTokenFile = tokenFile
+	})
+	status := func(token string) int {
+		return serve(srv, "/page?auth="+token).Code
+	}
+
+	if code := status("second"); code != http.StatusUnauthorized {
+		t.Fatalf("unknown token: got status %d, want %d", code, http.StatusUnauthorized)
+	}
+	if err := os.WriteFile(tokenFile, []byte("# rotated weekly\nfirst\nsecond\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"env", "first", "second"} {
+		if code := status(token); code != http.StatusOK {
+			t.Errorf("token %q: got status %d, want %d", token, code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package markdump
+
+type breadcrumb struct {
+	Title string
+	URL   string
+}
+
+// breadcrumbs returns links to the given dirs, starting at the root. The root link is replaced by srv.homeCrumb, or omitted if srv.HideHome is set.
+func (srv *Server) breadcrumbs(dirs []*Dir) []breadcrumb {
+	var crumbs = make([]breadcrumb, 0, len(dirs))
+	for _, dir := range dirs {
+		if len(dir.Path) > 0 {
+			crumbs = append(crumbs, breadcrumb{dir.title, dir.url})
+		} else if !srv.HideHome {
+			crumbs = append(crumbs, srv.homeCrumb())
+		}
+	}
+	return crumbs
+}
+
+// homeCrumb returns the root link with srv.HomeLabel and srv.HomeURL, which default to the root title and the root URL including the prefix.
+func (srv *Server) homeCrumb() breadcrumb {
+	var crumb = breadcrumb{
+		Title: srv.RootTitle,
+		URL:   srv.rootURL(),
+	}
+	if srv.HomeLabel != "" {
+		crumb.Title = srv.HomeLabel
+	}
+	if srv.HomeURL != "" {
+		crumb.URL = srv.HomeURL
+	}
+	return crumb
+}
@@ -0,0 +1,29 @@
+package markdump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHomeCrumb(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"guide/page.md": "Hello",
+	}, func(srv *Server) {
+		srv.HomeLabel = "Start"
+		srv.Prefix = "/wiki/"
+	})
+	body := serve(srv, "/wiki/guide/page").Body.String()
+	for _, want := range []string{
+		`<a href="/wiki">Start</a>`,
+		`<a href="/wiki/guide">guide</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("breadcrumbs do not contain %s", want)
+		}
+	}
+
+	srv.HideHome = true
+	if crumbs := srv.breadcrumbs(srv.Root().Subdirs["guide"].Path); len(crumbs) != 0 {
+		t.Errorf("HideHome: got %v, want no crumbs", crumbs)
+	}
+}
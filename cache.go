@@ -0,0 +1,80 @@
+package markdump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// renderVersion must be increased when the rendering changes in a way which is not reflected by renderOptions, so cached HTML is invalidated.
+const renderVersion = 2
+
+// renderOptions returns a string which represents the options affecting the rendered HTML.
+func (srv *Server) renderOptions() string {
+	return fmt.Sprintf("v%d references=%t strip-comments=%t external-link-rel=%t base-url=%s figures=%t details=%t admonitions=%t definition-lists=%t", renderVersion, srv.References, srv.StripComments, srv.ExternalLinkRel, srv.BaseURL, srv.Figures, srv.Details, srv.Admonitions, srv.DefinitionLists)
+}
+
+// render is like srv.render, but uses the render cache in srv.CacheDir if set. Files with includes are not cached because the included files might change.
+func (l *loader) render(fsPath string, mdContent []byte) template.HTML {
+	srv := l.srv
+	if srv.CacheDir == "" || hasIncludes(mdContent) {
+		return srv.render(l.fsDir, fsPath, mdContent, l.shared)
+	}
+
+	rel, err := filepath.Rel(l.fsDir, fsPath) // the content folder might be a temporary snapshot
+	if err != nil {
+		rel = fsPath
+	}
+	hash := sha256.New()
+	hash.Write([]byte(rel))
+	hash.Write([]byte{0})
+	hash.Write([]byte(srv.renderOptions()))
+	hash.Write([]byte{0})
+	hash.Write(mdContent)
+	name := hex.EncodeToString(hash.Sum(nil)) + ".html"
+	l.cached[name] = struct{}{}
+
+	cachePath := filepath.Join(srv.CacheDir, name)
+	if html, err := os.ReadFile(cachePath); err == nil {
+		return template.HTML(html)
+	}
+	html := srv.render(l.fsDir, fsPath, mdContent, l.shared)
+	if err := os.WriteFile(cachePath, []byte(html), 0o644); err != nil {
+		log.Printf("error writing render cache: %v", err)
+	}
+	return html
+}
+
+// pruneCache removes the cached files which have not been used by the loader.
+func (l *loader) pruneCache() {
+	if l.srv.CacheDir == "" {
+		return
+	}
+	entries, err := os.ReadDir(l.srv.CacheDir)
+	if err != nil {
+		log.Printf("error pruning render cache: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		if _, ok := l.cached[entry.Name()]; ok || !strings.HasSuffix(entry.Name(), ".html") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.srv.CacheDir, entry.Name())); err != nil {
+			log.Printf("error pruning render cache: %v", err)
+		}
+	}
+}
+
+func hasIncludes(mdContent []byte) bool {
+	var found bool
+	replaceLines(mdContent, includeDirective, func([][]byte) []byte {
+		found = true
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,46 @@
+package markdump
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Original",
+	}, func(srv *Server) {
+		srv.CacheDir = cacheDir
+	})
+	cached, err := filepath.Glob(filepath.Join(cacheDir, "*.html"))
+	if err != nil || len(cached) != 1 {
+		t.Fatalf("got cache files %v, %v, want one", cached, err)
+	}
+
+	// a warm cache skips rendering, so the modified cache file is used
+	if err := os.WriteFile(cached[0], []byte("<p>From cache</p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if html := string(srv.Root().Files["page"].HTMLContent); html != "<p>From cache</p>" {
+		t.Fatalf("warm cache: got %q", html)
+	}
+
+	// a content change invalidates the cache file
+	if err := os.WriteFile(filepath.Join(srv.FsDir, "page.md"), []byte("Changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if html := string(srv.Root().Files["page"].HTMLContent); !strings.Contains(html, "Changed") {
+		t.Fatalf("changed content: got %q", html)
+	}
+	if _, err := os.Stat(cached[0]); !os.IsNotExist(err) {
+		t.Fatal("outdated cache file has not been pruned")
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wansing/markdump"
+	"gopkg.in/yaml.v3"
+)
+
+// config is the optional CONFIG file in YAML or JSON format. Environment variables take precedence over the corresponding keys.
+type config struct {
+	Auth             []string      `yaml:"auth"`
+	AuthFile         string        `yaml:"auth_file"`
+	Cache            string        `yaml:"cache"`
+	DevMode          bool          `yaml:"dev_mode"`
+	GitRef           string        `yaml:"git_ref"`
+	Listen           string        `yaml:"listen"`
+	Mounts           []string      `yaml:"mounts"`
+	NoIndex          bool          `yaml:"noindex"`
+	ReloadSecret     string        `yaml:"reload_secret"`
+	Repo             string        `yaml:"repo"`
+	Robots           string        `yaml:"robots"`
+	Search           string        `yaml:"search"`
+	ServeUnavailable bool          `yaml:"serve_unavailable"`
+	Templates        string        `yaml:"templates"`
+	Title            string        `yaml:"title"`
+	Server           serverOptions `yaml:"server"` // options which have no environment variable
+}
+
+// serverOptions are the markdump.Server fields which can be set in the config file only.
+type serverOptions struct {
+	Admonitions          bool              `yaml:"admonitions"`
+	BackgroundIndex      bool              `yaml:"background_index"`
+	BaseURL              string            `yaml:"base_url"`
+	CollapseSingleChild  bool              `yaml:"collapse_single_child"`
+	ContentFragments     int               `yaml:"content_fragments"`
+	DateDirs             bool              `yaml:"date_dirs"`
+	DefinitionLists      bool              `yaml:"definition_lists"`
+	Details              bool              `yaml:"details"`
+	DirAssets            bool              `yaml:"dir_assets"`
+	ExcerptSource        excerptSource     `yaml:"excerpt_source"`
+	ExternalLinkRel      bool              `yaml:"external_link_rel"`
+	FailFast             bool              `yaml:"fail_fast"`
+	Figures              bool              `yaml:"figures"`
+	FoldAttachmentCase   bool              `yaml:"fold_attachment_case"`
+	HideHome             bool              `yaml:"hide_home"`
+	HighlightStrategy    highlightStrategy `yaml:"highlight_strategy"`
+	HomeLabel            string            `yaml:"home_label"`
+	HomeURL              string            `yaml:"home_url"`
+	HumanizeTitles       bool              `yaml:"humanize_titles"`
+	IncludeDrafts        bool              `yaml:"include_drafts"`
+	KeepDuplicateResults bool              `yaml:"keep_duplicate_results"`
+	KeepEmptyDirs        bool              `yaml:"keep_empty_dirs"`
+	LetterIndexThreshold int               `yaml:"letter_index_threshold"`
+	ListAttachments      bool              `yaml:"list_attachments"`
+	ListingLimit         int               `yaml:"listing_limit"`
+	LiveSearchDelay      time.Duration     `yaml:"live_search_delay"`
+	LooseFallback        bool              `yaml:"loose_fallback"`
+	MaxIndexedDocs       int               `yaml:"max_indexed_docs"`
+	MaxRenderedSize      int               `yaml:"max_rendered_size"`
+	MaxURLLength         int               `yaml:"max_url_length"`
+	MIMETypes            map[string]string `yaml:"mime_types"`
+	MinSearchTermLength  int               `yaml:"min_search_term_length"`
+	NegotiateImages      bool              `yaml:"negotiate_images"`
+	OmitReadmeResults    bool              `yaml:"omit_readme_results"`
+	PageSize             int               `yaml:"page_size"`
+	PathSeparator        string            `yaml:"path_separator"`
+	References           bool              `yaml:"references"`
+	RenderCSV            bool              `yaml:"render_csv"`
+	RootLandingFile      string            `yaml:"root_landing_file"`
+	SearchFields         []string          `yaml:"search_fields"`
+	SearchTimeout        time.Duration     `yaml:"search_timeout"`
+	ServeHTML            bool              `yaml:"serve_html"`
+	SharedDir            string            `yaml:"shared_dir"`
+	ShowRecent           int               `yaml:"show_recent"`
+	SidebarDepth         int               `yaml:"sidebar_depth"`
+	SlowSearchThreshold  time.Duration     `yaml:"slow_search_threshold"`
+	StripComments        bool              `yaml:"strip_comments"`
+	TitleFromH1          bool              `yaml:"title_from_h1"`
+	TitleH1Strip         bool              `yaml:"title_h1_strip"`
+	Transliterate        bool              `yaml:"transliterate"`
+}
+
+// excerptSource is a markdump.ExcerptSource which is given by name in the config file.
+type excerptSource markdump.ExcerptSource
+
+func (source *excerptSource) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Value {
+	case "", "auto":
+		*source = excerptSource(markdump.ExcerptAuto)
+	case "description":
+		*source = excerptSource(markdump.ExcerptDescription)
+	case "first_paragraph":
+		*source = excerptSource(markdump.ExcerptFirstParagraph)
+	case "none":
+		*source = excerptSource(markdump.ExcerptNone)
+	default:
+		return fmt.Errorf("line %d: unknown excerpt_source %q, expected auto, description, first_paragraph or none", value.Line, value.Value)
+	}
+	return nil
+}
+
+// highlightStrategy is a markdump.HighlightStrategy which is given by name in the config file.
+type highlightStrategy markdump.HighlightStrategy
+
+func (strategy *highlightStrategy) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Value {
+	case "", "best":
+		*strategy = highlightStrategy(markdump.HighlightBest)
+	case "first":
+		*strategy = highlightStrategy(markdump.HighlightFirst)
+	default:
+		return fmt.Errorf("line %d: unknown highlight_strategy %q, expected best or first", value.Line, value.Value)
+	}
+	return nil
+}
+
+// loadConfig reads the config file. Unknown keys are an error. If name is empty, it returns an empty config.
+func loadConfig(name string) (config, error) {
+	var cfg config
+	if name == "" {
+		return cfg, nil
+	}
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return cfg, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content)) // JSON is valid YAML
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return cfg, fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// apply sets the options on srv.
+func (opts serverOptions) apply(srv *markdump.Server) {
+	srv.Admonitions = opts.Admonitions
+	srv.BackgroundIndex = opts.BackgroundIndex
+	srv.BaseURL = opts.BaseURL
+	srv.CollapseSingleChild = opts.CollapseSingleChild
+	srv.ContentFragments = opts.ContentFragments
+	srv.DateDirs = opts.DateDirs
+	srv.DefinitionLists = opts.DefinitionLists
+	srv.Details = opts.Details
+	srv.DirAssets = opts.DirAssets
+	srv.ExcerptSource = markdump.ExcerptSource(opts.ExcerptSource)
+	srv.ExternalLinkRel = opts.ExternalLinkRel
+	srv.FailFast = opts.FailFast
+	srv.Figures = opts.Figures
+	srv.FoldAttachmentCase = opts.FoldAttachmentCase
+	srv.HideHome = opts.HideHome
+	srv.HighlightStrategy = markdump.HighlightStrategy(opts.HighlightStrategy)
+	srv.HomeLabel = opts.HomeLabel
+	srv.HomeURL = opts.HomeURL
+	srv.HumanizeTitles = opts.HumanizeTitles
+	srv.IncludeDrafts = opts.IncludeDrafts
+	srv.KeepDuplicateResults = opts.KeepDuplicateResults
+	srv.KeepEmptyDirs = opts.KeepEmptyDirs
+	srv.LetterIndexThreshold = opts.LetterIndexThreshold
+	srv.ListAttachments = opts.ListAttachments
+	srv.ListingLimit = opts.ListingLimit
+	srv.LiveSearchDelay = opts.LiveSearchDelay
+	srv.LooseFallback = opts.LooseFallback
+	srv.MaxIndexedDocs = opts.MaxIndexedDocs
+	srv.MaxRenderedSize = opts.MaxRenderedSize
+	srv.MaxURLLength = opts.MaxURLLength
+	srv.MIMETypes = opts.MIMETypes
+	srv.MinSearchTermLength = opts.MinSearchTermLength
+	srv.NegotiateImages = opts.NegotiateImages
+	srv.OmitReadmeResults = opts.OmitReadmeResults
+	srv.PageSize = opts.PageSize
+	srv.PathSeparator = opts.PathSeparator
+	srv.References = opts.References
+	srv.RenderCSV = opts.RenderCSV
+	srv.RootLandingFile = opts.RootLandingFile
+	srv.SearchFields = opts.SearchFields
+	srv.SearchTimeout = opts.SearchTimeout
+	srv.ServeHTML = opts.ServeHTML
+	srv.SharedDir = opts.SharedDir
+	srv.ShowRecent = opts.ShowRecent
+	srv.SidebarDepth = opts.SidebarDepth
+	srv.SlowSearchThreshold = opts.SlowSearchThreshold
+	srv.StripComments = opts.StripComments
+	srv.TitleFromH1 = opts.TitleFromH1
+	srv.TitleH1Strip = opts.TitleH1Strip
+	srv.Transliterate = opts.Transliterate
+}
+
+// envString returns the environment variable, or fallback if it is empty.
+func envString(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envFields returns the whitespace-separated fields of the environment variable, or fallback if there are none.
+func envFields(name string, fallback []string) []string {
+	if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+		return fields
+	}
+	return fallback
+}
+
+// envBool parses the environment variable like strconv.ParseBool, so "false" or "0" override a true fallback. It returns fallback if the variable is empty.
+func envBool(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Fatalf("invalid %s %q, expected true or false", name, value)
+	}
+	return b
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wansing/markdump"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	name = filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := loadConfig(writeConfig(t, "config.yaml", `
+auth: [one, two]
+dev_mode: true
+listen: 127.0.0.1:9000
+title: Handbook
+server:
+  content_fragments: 2
+  excerpt_source: first_paragraph
+  highlight_strategy: first
+  live_search_delay: 300ms
+  mime_types:
+    .mjs: text/javascript
+  search_fields: [name]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cfg.Auth, []string{"one", "two"}) || !cfg.DevMode || cfg.Listen != "127.0.0.1:9000" || cfg.Title != "Handbook" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	var srv markdump.Server
+	cfg.Server.apply(&srv)
+	if srv.ContentFragments != 2 ||
+		srv.ExcerptSource != markdump.ExcerptFirstParagraph ||
+		srv.HighlightStrategy != markdump.HighlightFirst ||
+		srv.LiveSearchDelay != 300*time.Millisecond ||
+		srv.MIMETypes[".mjs"] != "text/javascript" ||
+		!slices.Equal(srv.SearchFields, []string{"name"}) {
+		t.Errorf("server options are not applied")
+	}
+
+	cfg, err = loadConfig(writeConfig(t, "config.json", `{"title": "JSON", "server": {"page_size": 10}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Title != "JSON" || cfg.Server.PageSize != 10 {
+		t.Errorf("JSON: got %+v", cfg)
+	}
+
+	for _, content := range []string{
+		"titel: Typo",
+		"server:\n  highlight_strategy: longest",
+		"server:\n  excerpt_source: summary",
+	} {
+		if _, err := loadConfig(writeConfig(t, "config.yaml", content)); err == nil {
+			t.Errorf("%q: got no error", content)
+		}
+	}
+
+	if cfg, err := loadConfig(""); err != nil || cfg.Title != "" {
+		t.Errorf("empty name: got %+v, %v", cfg, err)
+	}
+}
+
+func TestEnvBool(t *testing.T) {
+	for _, test := range []struct {
+		value    string
+		fallback bool
+		want     bool
+	}{
+		{"", true, true},
+		{"", false, false},
+		{"false", true, false},
+		{"0", true, false},
+		{"true", false, true},
+		{"1", false, true},
+	} {
+		t.Setenv("MARKDUMP_TEST_BOOL", test.value)
+		if got := envBool("MARKDUMP_TEST_BOOL", test.fallback); got != test.want {
+			t.Errorf("%q with fallback %t: got %t, want %t", test.value, test.fallback, got, test.want)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/wansing/markdump"
@@ -14,15 +16,25 @@ import (
 )
 
 func main() {
-	authTokens := strings.Fields(os.Getenv("AUTH"))
-	if len(authTokens) == 0 {
-		log.Fatalln("AUTH missing")
+	cfg, err := loadConfig(os.Getenv("CONFIG"))
+	if err != nil {
+		log.Fatalf("error loading config: %v", err)
 	}
-	listen := os.Getenv("LISTEN")
+
+	authTokens := envFields("AUTH", cfg.Auth)
+	authTokenFile := envString("AUTH_FILE", cfg.AuthFile)
+	if len(authTokens) == 0 && authTokenFile == "" {
+		log.Fatalln("AUTH or AUTH_FILE missing")
+	}
+	cacheDir := envString("CACHE", cfg.Cache)
+	devMode := envBool("DEV_MODE", cfg.DevMode)
+	gitRef := envString("GIT_REF", cfg.GitRef)
+	listen := envString("LISTEN", cfg.Listen)
 	if listen == "" {
 		listen = "127.0.0.1:8134"
 	}
-	reloadSecret := os.Getenv("RELOAD_SECRET")
+	noIndex := envBool("NOINDEX", cfg.NoIndex)
+	reloadSecret := envString("RELOAD_SECRET", cfg.ReloadSecret)
 	if reloadSecret == "" {
 		var bs = make([]byte, 16)
 		if _, err := rand.Read(bs); err != nil {
@@ -31,31 +43,103 @@ func main() {
 		reloadSecret = base64.RawURLEncoding.EncodeToString(bs)
 		log.Printf("generated temporary reload secret: %s", reloadSecret)
 	}
-	repoDir := os.Getenv("REPO")
+	repoDir := envString("REPO", cfg.Repo)
 	if repoDir == "" {
 		repoDir = "."
 	}
-	rootTitle := os.Getenv("TITLE")
+	var templates fs.FS
+	if templateDir := envString("TEMPLATES", cfg.Templates); templateDir != "" {
+		templates = os.DirFS(templateDir)
+	}
+	robotsPolicy := envString("ROBOTS", cfg.Robots)
+	serveUnavailable := envBool("SERVE_UNAVAILABLE", cfg.ServeUnavailable)
+	searchBackend := envString("SEARCH", cfg.Search)
+	if searchBackend != "" && searchBackend != "bluge" && searchBackend != "substring" {
+		log.Fatalf("unknown SEARCH %q", searchBackend)
+	}
+	rootTitle := envString("TITLE", cfg.Title)
 	if rootTitle == "" {
 		rootTitle = "Home"
 	}
 
-	srv := &markdump.Server{
-		AuthTokens: authTokens,
-		FsDir:      repoDir,
-		RootTitle:  rootTitle,
-	}
-	if err := srv.Reload(); err != nil {
-		log.Fatalf("error loading: %v", err)
+	var servers []*markdump.Server
+	if mounts := envFields("MOUNTS", cfg.Mounts); len(mounts) > 0 {
+		for _, mount := range mounts {
+			prefix, dir, ok := strings.Cut(mount, "=")
+			if !ok {
+				log.Fatalf("invalid mount %q, expected prefix=dir", mount)
+			}
+			title := markdump.Humanize(strings.Trim(prefix, "/"))
+			if title == "" {
+				title = rootTitle
+			}
+			var mountCacheDir string
+			if cacheDir != "" {
+				mountCacheDir = filepath.Join(cacheDir, markdump.Slugify(prefix)) // each server prunes its own cache
+			}
+			servers = append(servers, &markdump.Server{
+				AuthTokenFile: authTokenFile,
+				AuthTokens:    authTokens,
+				CacheDir:      mountCacheDir,
+				DevMode:       devMode,
+				FsDir:         dir,
+				GitRef:        gitRef,
+				NoIndex:       noIndex,
+				Prefix:        prefix,
+				RobotsPolicy:  robotsPolicy,
+				RootTitle:     title,
+				Templates:     templates,
+			})
+		}
+	} else {
+		servers = append(servers, &markdump.Server{
+			AuthTokenFile: authTokenFile,
+			AuthTokens:    authTokens,
+			CacheDir:      cacheDir,
+			DevMode:       devMode,
+			FsDir:         repoDir,
+			GitRef:        gitRef,
+			NoIndex:       noIndex,
+			RobotsPolicy:  robotsPolicy,
+			RootTitle:     rootTitle,
+			Templates:     templates,
+		})
 	}
 
-	reloadHandler := seal.GitReloadHandler(reloadSecret, repoDir, srv.Reload)
+	http.Handle("GET /static/", http.StripPrefix("/static", static.Handler()))
+	http.HandleFunc("GET /robots.txt", servers[0].HandleRobots) // robots.txt applies to the whole host
+	for _, srv := range servers {
+		cfg.Server.apply(srv)
+		if searchBackend == "substring" {
+			srv.Searcher = markdump.NewSubstringSearcher()
+		}
+		if err := srv.Reload(); err != nil {
+			if !serveUnavailable {
+				log.Fatalf("error loading %s: %v", srv.FsDir, err)
+			}
+			log.Printf("error loading %s, serving 503 until a reload succeeds: %v", srv.FsDir, err)
+		}
+
+		prefix := "/"
+		if p := strings.Trim(srv.Prefix, "/"); p != "" {
+			prefix = "/" + p + "/"
+		}
+		reloadHandler := seal.GitReloadHandler(reloadSecret, srv.FsDir, srv.Reload)
+
+		http.Handle("GET "+prefix, srv)
+		http.HandleFunc("GET "+prefix+"reload", reloadHandler)
+		http.HandleFunc("POST "+prefix+"reload", reloadHandler)
+		http.HandleFunc("GET "+prefix+"search", srv.HandleSearchAPI)
+		http.HandleFunc("GET "+prefix+"suggest", srv.HandleSuggest)
+		http.HandleFunc("GET "+prefix+"openapi.json", srv.HandleOpenAPI)
+		http.HandleFunc("GET "+prefix+"opensearch.xml", srv.HandleOpenSearch)
+		http.HandleFunc("GET "+prefix+"api/dir", srv.HandleDirAPI)
+		http.HandleFunc("GET "+prefix+"recent.json", srv.HandleRecent)
+		http.HandleFunc("GET "+prefix+"routes.json", srv.HandleRoutes)
+		http.HandleFunc("GET "+prefix+"sitemap.xml", srv.HandleSitemap)
+		http.HandleFunc("GET "+prefix+"livereload", srv.HandleLiveReload)
+	}
 
 	log.Printf("listening to %s", listen)
-	http.Handle("GET /", srv)
-	http.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.FS(static.Files))))
-	http.HandleFunc("GET /reload", reloadHandler)
-	http.HandleFunc("POST /reload", reloadHandler)
-	http.HandleFunc("GET /search", srv.HandleSearchAPI)
-	http.ListenAndServe(listen, nil)
+	http.ListenAndServe(listen, markdump.RequestID(http.DefaultServeMux))
 }
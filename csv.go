@@ -0,0 +1,49 @@
+package markdump
+
+import (
+	"bytes"
+	"encoding/csv"
+	"html/template"
+)
+
+// maxCSVRows is the maximum number of rendered rows of a CSV file, excluding the header.
+const maxCSVRows = 1000
+
+var csvTmpl = template.Must(template.New("csv").Parse(`<div class="table-responsive"><table class="table table-sm table-striped">
+{{- with .Header}}<thead><tr>{{range .}}<th>{{.}}</th>{{end}}</tr></thead>{{end -}}
+<tbody>{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</tbody></table></div>
+{{- with .Notice}}<p class="text-body-secondary">{{.}}</p>{{end}}
+<p><a href="{{.Name}}" download>{{.Name}}</a></p>`))
+
+// renderCSV renders the CSV content as an HTML table, using the first row as header. It renders at most maxCSVRows rows and links the raw file with the given name.
+func renderCSV(content []byte, name string) ([]byte, error) {
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1 // allow ragged rows
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Header []string
+		Rows   [][]string
+		Notice template.HTML // if truncated
+		Name   string
+	}
+	data.Name = name
+	if len(records) > 0 {
+		data.Header = records[0]
+		records = records[1:]
+	}
+	data.Rows = records[:min(len(records), maxCSVRows)]
+	if len(records) > maxCSVRows {
+		data.Notice = contentMessage("Showing the first %d of %d rows.", len(data.Rows), len(records))
+	}
+
+	var buf bytes.Buffer
+	if err := csvTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+package markdump
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestRenderCSV(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"data.csv": "name,amount\nApples,3\n\"Pears, ripe\",<5>\n",
+	}, func(srv *Server) {
+		srv.RenderCSV = true
+	})
+	body := serve(srv, "/data").Body.String()
+	for _, want := range []string{
+		`<thead><tr><th>name</th><th>amount</th></tr></thead>`,
+		`<tr><td>Apples</td><td>3</td></tr>`,
+		`<tr><td>Pears, ripe</td><td>&lt;5&gt;</td></tr>`,
+		`<a href="data.csv" download>data.csv</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("table does not contain %s: %s", want, body)
+		}
+	}
+	if raw := serve(srv, "/data.csv").Body.String(); !strings.HasPrefix(raw, "name,amount\n") {
+		t.Errorf("got raw file %q", raw)
+	}
+	if hrefs := searchHrefs(t, srv, "apples"); len(hrefs) != 1 || hrefs[0] != "/data" {
+		t.Errorf("got search results %v, want /data", hrefs)
+	}
+
+	html, err := renderCSV([]byte("n\n"+strings.Repeat("1\n", maxCSVRows+5)), "big.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(html), "<tr>"); got != maxCSVRows+1 {
+		t.Errorf("got %d rows, want %d", got, maxCSVRows+1)
+	}
+	if !strings.Contains(string(localize("en", template.HTML(html))), "Showing the first 1000 of 1005 rows.") {
+		t.Errorf("truncation notice is missing")
+	}
+}
@@ -0,0 +1,59 @@
+package markdump
+
+import (
+	"strconv"
+	"time"
+)
+
+// dirDate is the date of a dir in a date-structured tree like 2024/01/15, see Server.DateDirs.
+type dirDate struct {
+	time  time.Time
+	parts int // 0: none, 1: year, 2: year and month, 3: full date
+}
+
+// child returns the date of the subdir with the given name, which extends date by one part.
+func (date dirDate) child(name string) dirDate {
+	n, err := strconv.Atoi(name)
+	if err != nil || len(name) > 4 {
+		return dirDate{}
+	}
+	switch date.parts {
+	case 0:
+		if len(name) == 4 {
+			return dirDate{time.Date(n, 1, 1, 0, 0, 0, 0, time.UTC), 1}
+		}
+	case 1:
+		if len(name) <= 2 && n >= 1 && n <= 12 {
+			return dirDate{date.time.AddDate(0, n-1, 0), 2}
+		}
+	case 2:
+		if day := date.time.AddDate(0, 0, n-1); len(name) <= 2 && n >= 1 && day.Month() == date.time.Month() {
+			return dirDate{day, 3}
+		}
+	}
+	return dirDate{}
+}
+
+// entryDate returns the date of a file or dir in a date-structured tree, or the zero time.
+func entryDate(entry Entry) time.Time {
+	switch entry := entry.(type) {
+	case *Dir:
+		return entry.date.time
+	case *File:
+		return entry.Date
+	}
+	return time.Time{}
+}
+
+// lessByDate orders entries with dates chronologically before entries without dates, which are ordered by URL.
+func lessByDate(a, b Entry) bool {
+	dateA, dateB := entryDate(a), entryDate(b)
+	switch {
+	case dateA.IsZero() != dateB.IsZero():
+		return !dateA.IsZero()
+	case !dateA.Equal(dateB):
+		return dateA.Before(dateB)
+	default:
+		return a.URL() < b.URL()
+	}
+}
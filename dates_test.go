@@ -0,0 +1,51 @@
+package markdump
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDateDirs(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"2024/10/1/autumn.md":  "Autumn",
+		"2024/2/15/winter.md":  "Winter",
+		"2024/2/9/earlier.md":  "Earlier",
+		"2024/2/31/invalid.md": "No such day",
+		"2024/notes.md":        "Undated",
+	}, func(srv *Server) {
+		srv.DateDirs = true
+	})
+	root := srv.current.Load().root
+
+	var files []string
+	var walk func(dir *Dir)
+	walk = func(dir *Dir) {
+		for _, entry := range dir.EntryList {
+			switch entry := entry.(type) {
+			case *Dir:
+				walk(entry)
+			case *File:
+				files = append(files, entry.URL())
+				var want time.Time
+				switch entry.URL() {
+				case "/2024/10/1/autumn":
+					want = time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+				case "/2024/2/15/winter":
+					want = time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+				case "/2024/2/9/earlier":
+					want = time.Date(2024, 2, 9, 0, 0, 0, 0, time.UTC)
+				}
+				if !entry.Date.Equal(want) {
+					t.Errorf("%s: got date %v, want %v", entry.URL(), entry.Date, want)
+				}
+			}
+		}
+	}
+	walk(root)
+
+	want := []string{"/2024/2/9/earlier", "/2024/2/15/winter", "/2024/2/31/invalid", "/2024/10/1/autumn", "/2024/notes"}
+	if !slices.Equal(files, want) {
+		t.Fatalf("got order %v, want %v", files, want)
+	}
+}
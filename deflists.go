@@ -0,0 +1,57 @@
+package markdump
+
+import (
+	"regexp"
+	"strings"
+)
+
+var paragraph = regexp.MustCompile(`(?s)<p>(.*?)</p>\n`)
+
+// renderDefinitionLists turns paragraphs like "Term\n: Definition" into definition lists. Consecutive items are merged into one list.
+func renderDefinitionLists(html string) string {
+	var sb strings.Builder
+	var open bool // within a <dl>
+	var last int  // end of the previous item
+	for _, loc := range paragraph.FindAllStringSubmatchIndex(html, -1) {
+		terms, defs, ok := definitionItem(html[loc[2]:loc[3]])
+		if !ok {
+			continue
+		}
+		if !open || loc[0] != last {
+			if open {
+				sb.WriteString("</dl>\n")
+			}
+			sb.WriteString(html[last:loc[0]])
+			sb.WriteString("<dl>\n")
+			open = true
+		}
+		for _, term := range terms {
+			sb.WriteString("<dt>" + term + "</dt>\n")
+		}
+		for _, def := range defs {
+			sb.WriteString("<dd>" + def + "</dd>\n")
+		}
+		last = loc[1]
+	}
+	if open {
+		sb.WriteString("</dl>\n")
+	}
+	sb.WriteString(html[last:])
+	return sb.String()
+}
+
+// definitionItem splits the content of a paragraph into one or more term lines and the following definition lines, which start with ": ".
+func definitionItem(content string) ([]string, []string, bool) {
+	lines := strings.Split(content, "\n")
+	var terms, defs []string
+	for _, line := range lines {
+		if def, ok := strings.CutPrefix(line, ": "); ok {
+			defs = append(defs, strings.TrimSpace(def))
+		} else if len(defs) == 0 {
+			terms = append(terms, line)
+		} else {
+			return nil, nil, false // term after definition
+		}
+	}
+	return terms, defs, len(terms) > 0 && len(defs) > 0
+}
@@ -0,0 +1,69 @@
+package markdump
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// matches the opening fence of a details block like ```details Title
+var detailsFence = regexp.MustCompile("^\\s*(`{3,}|~{3,})details(?:\\s+(.*?))?\\s*$")
+
+// renderDetails replaces fenced details blocks by placeholders and returns the rendered blocks. Their content is rendered as markdown, so they can be nested by using longer fences for the outer block.
+func renderDetails(mdContent []byte) ([]byte, []string) {
+	var result = make([]byte, 0, len(mdContent))
+	var blocks []string
+	var fence string       // of an ordinary code block
+	var open string        // fence of the current details block
+	var summary string     // of the current details block
+	var inner bytes.Buffer // content of the current details block
+	for _, line := range bytes.SplitAfter(mdContent, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+		switch {
+		case open != "":
+			if strings.Trim(trimmed, open[:1]) == "" && len(trimmed) >= len(open) {
+				nested, nestedBlocks := renderDetails(inner.Bytes())
+				content := md.RenderToString(nested)
+				for i, block := range nestedBlocks {
+					content = strings.Replace(content, detailsPlaceholder(i), block, 1)
+				}
+				blocks = append(blocks, "<details><summary>"+html.EscapeString(summary)+"</summary>"+content+"</details>")
+				result = append(result, detailsPlaceholder(len(blocks)-1)+"\n"...)
+				open, summary = "", ""
+				inner.Reset()
+				continue
+			}
+			inner.Write(line)
+			continue
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		default:
+			if m := detailsFence.FindSubmatch(bytes.TrimRight(line, "\r\n")); m != nil {
+				open, summary = string(m[1]), string(m[2])
+				if summary == "" {
+					summary = "Details"
+				}
+				continue
+			}
+			if strings.HasPrefix(trimmed, "```") {
+				fence = "```"
+			} else if strings.HasPrefix(trimmed, "~~~") {
+				fence = "~~~"
+			}
+		}
+		result = append(result, line...)
+	}
+	if open != "" {
+		result = append(result, open+"details "+summary+"\n"...) // unclosed, keep as is
+		result = append(result, inner.Bytes()...)
+	}
+	return result, blocks
+}
+
+func detailsPlaceholder(i int) string {
+	return fmt.Sprintf("<!--markdump-details-%d-->", i)
+}
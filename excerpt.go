@@ -0,0 +1,43 @@
+package markdump
+
+import (
+	"html/template"
+	"regexp"
+)
+
+// ExcerptSource determines how the excerpt of a file is derived.
+type ExcerptSource int
+
+const (
+	ExcerptAuto           ExcerptSource = iota // description from front matter, or first paragraph
+	ExcerptDescription                         // description from front matter only
+	ExcerptFirstParagraph                      // first paragraph only
+	ExcerptNone
+)
+
+// maxExcerptLength is the maximum number of runes of an excerpt.
+const maxExcerptLength = 200
+
+var firstParagraph = regexp.MustCompile(`(?s)<p>(.*?)</p>`)
+
+// excerpt returns the excerpt of a file with the given description and rendered content.
+func (srv *Server) excerpt(description string, htmlContent template.HTML) string {
+	var source = srv.ExcerptSource
+	if source == ExcerptAuto {
+		if description != "" {
+			source = ExcerptDescription
+		} else {
+			source = ExcerptFirstParagraph
+		}
+	}
+	switch source {
+	case ExcerptDescription:
+		return truncate(maxExcerptLength, description)
+	case ExcerptFirstParagraph:
+		content := stripAuthOnly([]byte(htmlContent)) // excerpts might be displayed to anonymous users
+		if match := firstParagraph.FindSubmatch(content); match != nil {
+			return truncate(maxExcerptLength, htmlText(match[1]))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,30 @@
+package markdump
+
+import "testing"
+
+func TestExcerpt(t *testing.T) {
+	files := map[string]string{
+		"described.md": "---\ndescription: From front matter\n---\n# Title\n\nFirst paragraph.\n\nSecond paragraph.",
+		"plain.md":     "# Title\n\nFirst paragraph.\n\nSecond paragraph.",
+	}
+	for _, test := range []struct {
+		source    ExcerptSource
+		described string
+		plain     string
+	}{
+		{ExcerptAuto, "From front matter", "First paragraph."},
+		{ExcerptDescription, "From front matter", ""},
+		{ExcerptFirstParagraph, "First paragraph.", "First paragraph."},
+		{ExcerptNone, "", ""},
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.ExcerptSource = test.source
+		})
+		if got := srv.Root().Files["described"].Excerpt; got != test.described {
+			t.Errorf("source %d, with description: got %q, want %q", test.source, got, test.described)
+		}
+		if got := srv.Root().Files["plain"].Excerpt; got != test.plain {
+			t.Errorf("source %d, without description: got %q, want %q", test.source, got, test.plain)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package markdump
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	anchorTag  = regexp.MustCompile(`(?is)<a\s[^>]*>`)
+	anchorHref = regexp.MustCompile(`(?is)\shref\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	anchorAttr = regexp.MustCompile(`(?is)\s(rel|target)\s*=`)
+)
+
+// markExternalLinks adds rel="nofollow ugc noopener" and target="_blank" to links which point to another host than baseURL. Links which already have a rel or target attribute are kept.
+func markExternalLinks(content string, baseURL string) string {
+	var host string
+	if u, err := url.Parse(baseURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	return anchorTag.ReplaceAllStringFunc(content, func(tag string) string {
+		m := anchorHref.FindStringSubmatch(tag)
+		if m == nil || anchorAttr.MatchString(tag) {
+			return tag
+		}
+		href := m[1] + m[2]
+		if !isExternal(html.UnescapeString(href), host) {
+			return tag
+		}
+		return tag[:len(tag)-1] + ` rel="nofollow ugc noopener" target="_blank">`
+	})
+}
+
+// isExternal reports whether href is an absolute http(s) link to another host than host.
+func isExternal(href, host string) bool {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || u.Host == "" {
+		return false // relative link
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	return !strings.EqualFold(u.Hostname(), host)
+}
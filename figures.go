@@ -0,0 +1,20 @@
+package markdump
+
+import "regexp"
+
+var (
+	standaloneImage = regexp.MustCompile(`(?s)<p>\s*(<img\s[^>]*>)\s*</p>`)
+	imageAlt        = regexp.MustCompile(`\salt="([^"]*)"`)
+)
+
+// wrapFigures wraps images which are alone in a paragraph in a figure, using their alt text as caption. Inline images and images without alt text are kept.
+func wrapFigures(content string) string {
+	return standaloneImage.ReplaceAllStringFunc(content, func(p string) string {
+		img := standaloneImage.FindStringSubmatch(p)[1]
+		m := imageAlt.FindStringSubmatch(img)
+		if m == nil || m[1] == "" {
+			return p
+		}
+		return "<figure>" + img + "<figcaption>" + m[1] + "</figcaption></figure>" // alt is escaped already
+	})
+}
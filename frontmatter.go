@@ -0,0 +1,51 @@
+package markdump
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter is the optional YAML header of a markdown file, delimited by "---" lines.
+type frontMatter struct {
+	Aliases     []string `yaml:"aliases"`     // additional URL paths which redirect to the file
+	Canonical   string   `yaml:"canonical"`   // URL or path of the preferred page for search engines
+	Description string   `yaml:"description"` // see Server.ExcerptSource
+	Draft       bool     `yaml:"draft"`       // skipped unless Server.IncludeDrafts is set
+	Slug        string   `yaml:"slug"`        // replaces the slug derived from the file name
+	Title       string   `yaml:"title"`
+}
+
+// splitFrontMatter parses the front matter, if any, and returns it along with the remaining markdown.
+func splitFrontMatter(source []byte) (frontMatter, []byte, error) {
+	var fm frontMatter
+	rest, ok := bytes.CutPrefix(source, []byte("---\n"))
+	if !ok {
+		rest, ok = bytes.CutPrefix(source, []byte("---\r\n"))
+	}
+	if !ok {
+		return fm, source, nil
+	}
+
+	// find closing line
+	var header []byte
+	for offset := 0; offset < len(rest); {
+		line := rest[offset:]
+		end := bytes.IndexByte(line, '\n')
+		if end < 0 {
+			end = len(line)
+		} else {
+			end++
+		}
+		if string(bytes.TrimRight(line[:end], "\r\n")) == "---" {
+			header = rest[:offset]
+			rest = rest[offset+end:]
+			if err := yaml.Unmarshal(header, &fm); err != nil {
+				return frontMatter{}, source, err
+			}
+			return fm, rest, nil
+		}
+		offset += end
+	}
+	return fm, source, nil // no closing line
+}
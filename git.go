@@ -0,0 +1,99 @@
+package markdump
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// snapshotGitRef extracts the files of the given ref of the git repository in repoDir into a new temporary folder. The working tree is not touched. Symlinks are skipped.
+func snapshotGitRef(repoDir, ref string) (string, error) {
+	dir, err := os.MkdirTemp("", "markdump-")
+	if err != nil {
+		return "", err
+	}
+	if err := extractGitRef(repoDir, ref, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func extractGitRef(repoDir, ref, dst string) error {
+	cmd := exec.Command("git", "-C", repoDir, "archive", "--format=tar", ref, "--")
+	var stderr limitedBuffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	extractErr := extractTar(stdout, dst)
+	io.Copy(io.Discard, stdout) // let git finish if extracting failed
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git archive %s: %w: %s", ref, err, stderr)
+	}
+	return extractErr
+}
+
+func extractTar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !filepath.IsLocal(header.Name) {
+			return fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+		target := filepath.Join(dst, filepath.FromSlash(header.Name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Chtimes(target, header.ModTime, header.ModTime); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// limitedBuffer keeps the first 1 KiB written to it.
+type limitedBuffer []byte
+
+func (buf *limitedBuffer) Write(p []byte) (int, error) {
+	if n := 1024 - len(*buf); n > 0 {
+		*buf = append(*buf, p[:min(n, len(p))]...)
+	}
+	return len(p), nil
+}
+
+func (buf limitedBuffer) String() string {
+	return string(buf)
+}
@@ -0,0 +1,69 @@
+package markdump
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs git with the given arguments in dir.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, output)
+	}
+}
+
+func TestGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	repo := writeTree(t, map[string]string{
+		"page.md": "Stable content.",
+	})
+	git(t, repo, "init", "-q", "-b", "stable")
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "stable")
+	git(t, repo, "checkout", "-q", "-b", "next")
+	if err := os.WriteFile(filepath.Join(repo, "page.md"), []byte("Next content."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "new.md"), []byte("New page."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, repo, "add", "-A")
+	git(t, repo, "commit", "-q", "-m", "next")
+	if err := os.WriteFile(filepath.Join(repo, "page.md"), []byte("Uncommitted content."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		ref     string
+		content string
+		newPage bool
+	}{
+		{"stable", "Stable content.", false},
+		{"next", "Next content.", true},
+	} {
+		srv := &Server{
+			AuthTokens: []string{"public"},
+			FsDir:      repo,
+			GitRef:     test.ref,
+		}
+		if err := srv.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			os.RemoveAll(srv.current.Load().snapshot)
+		})
+		if body := serve(srv, "/page").Body.String(); !strings.Contains(body, test.content) {
+			t.Errorf("ref %s: page does not contain %q", test.ref, test.content)
+		}
+		if _, ok := srv.Root().Files["new"]; ok != test.newPage {
+			t.Errorf("ref %s: got new page %t, want %t", test.ref, ok, test.newPage)
+		}
+	}
+}
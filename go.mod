@@ -7,6 +7,7 @@ require (
 	github.com/wansing/seal v0.0.0-20240111173814-9ef919c16cbd
 	gitlab.com/golang-commonmark/markdown v0.0.0-20211110145824-bf3e522c626a
 	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
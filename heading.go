@@ -0,0 +1,35 @@
+package markdump
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+
+	"gitlab.com/golang-commonmark/markdown"
+)
+
+// titleHeading returns the plain text of the first level-one heading in mdContent, and mdContent without the lines of that heading.
+func titleHeading(mdContent []byte) (string, []byte, bool) {
+	tokens := md.Parse(mdContent)
+	for i, token := range tokens {
+		heading, ok := token.(*markdown.HeadingOpen)
+		if !ok || heading.HLevel != 1 || i+1 >= len(tokens) {
+			continue
+		}
+		inline, ok := tokens[i+1].(*markdown.Inline)
+		if !ok {
+			continue
+		}
+		title := strings.Join(strings.Fields(htmlText([]byte(md.RenderToString([]byte(inline.Content))))), " ")
+		if title == "" {
+			continue
+		}
+		lines := bytes.SplitAfter(mdContent, []byte("\n"))
+		if heading.Map[1] > len(lines) {
+			return title, mdContent, true
+		}
+		rest := bytes.Join(append(slices.Clip(lines[:heading.Map[0]]), lines[heading.Map[1]:]...), nil)
+		return title, rest, true
+	}
+	return "", mdContent, false
+}
@@ -0,0 +1,39 @@
+package markdump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTitleFromH1(t *testing.T) {
+	files := map[string]string{
+		"heading.md":      "Intro\n\n# The *Real* Title\n\nBody",
+		"front-matter.md": "---\ntitle: From Front Matter\n---\n# Heading\n\nBody",
+		"plain.md":        "## Second level\n\nBody",
+	}
+	for _, test := range []struct {
+		fromH1, strip bool
+		titles        map[string]string
+	}{
+		{false, false, map[string]string{"heading": "heading", "front-matter": "From Front Matter", "plain": "plain"}},
+		{true, false, map[string]string{"heading": "The Real Title", "front-matter": "From Front Matter", "plain": "plain"}},
+		{true, true, map[string]string{"heading": "The Real Title", "front-matter": "From Front Matter", "plain": "plain"}},
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.TitleFromH1 = test.fromH1
+			srv.TitleH1Strip = test.strip
+		})
+		root := srv.Root()
+		for slug, want := range test.titles {
+			if got := root.Files[slug].Title(); got != want {
+				t.Errorf("TitleFromH1 %t: %s: got title %q, want %q", test.fromH1, slug, got, want)
+			}
+		}
+		if stripped := !strings.Contains(string(root.Files["heading"].HTMLContent), "<h1>"); stripped != test.strip {
+			t.Errorf("TitleFromH1 %t, TitleH1Strip %t: got heading stripped %t", test.fromH1, test.strip, stripped)
+		}
+		if html := string(root.Files["heading"].HTMLContent); !strings.Contains(html, "<p>Intro</p>") || !strings.Contains(html, "<p>Body</p>") {
+			t.Errorf("content around the heading is missing: %s", html)
+		}
+	}
+}
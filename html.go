@@ -2,43 +2,257 @@ package markdump
 
 import (
 	"embed"
+	"errors"
+	"fmt"
 	"html/template"
+	"io/fs"
+	"maps"
+	"time"
+
+	"github.com/wansing/markdump/static"
 )
 
 //go:embed *.html
 var files embed.FS
 
-func parse(fn ...string) *template.Template {
-	return template.Must(template.New(fn[0]).ParseFS(files, fn...))
+// builtinFuncs are available in all templates.
+var builtinFuncs = template.FuncMap{
+	"formatDate": formatDate,
+	"reltime":    reltime,
+	"slugify":    Slugify,
+	"static":     static.Path,
+	"t":          translate,
+	"truncate":   truncate,
 }
 
-var (
-	dirTmpl    = parse("layout.html", "dir.html")
-	fileTmpl   = parse("layout.html", "file.html")
-	searchTmpl = parse("layout.html", "search.html")
-)
+// formatDate formats t according to layout, e.g. {{.ModTime | formatDate "2006-01-02"}}.
+func formatDate(layout string, t time.Time) string {
+	return t.Format(layout)
+}
+
+// reltime formats t relative to the current time, e.g. "3 days ago". Times more than a year away are formatted as dates.
+func reltime(t time.Time) string {
+	return relativeTime(t, time.Now())
+}
+
+func relativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var s string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		s = fmt.Sprintf("%d min", d/time.Minute)
+	case d < 24*time.Hour:
+		s = plural(int(d/time.Hour), "hour")
+	case d < 30*24*time.Hour:
+		s = plural(int(d/(24*time.Hour)), "day")
+	case d < 365*24*time.Hour:
+		s = plural(int(d/(30*24*time.Hour)), "month")
+	default:
+		return t.Format("2006-01-02")
+	}
+	if future {
+		return "in " + s
+	}
+	return s + " ago"
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// truncate shortens s to at most n runes, including an ellipsis, e.g. {{.Title | truncate 20}}.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if len(runes) <= n || n <= 0 {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// templates are parsed by Reload, so custom functions and override templates can be used.
+type templates struct {
+	dir    *template.Template
+	error  *template.Template
+	file   *template.Template
+	index  *template.Template
+	login  *template.Template
+	search *template.Template
+	source *template.Template
+}
+
+// parseTemplates parses all templates. They share layout.html and partials.html.
+func (srv *Server) parseTemplates() (*templates, error) {
+	var tmpls templates
+	for _, t := range []struct {
+		dst  **template.Template
+		file string
+	}{
+		{&tmpls.dir, "dir.html"},
+		{&tmpls.error, "error.html"},
+		{&tmpls.file, "file.html"},
+		{&tmpls.index, "index.html"},
+		{&tmpls.login, "login.html"},
+		{&tmpls.search, "search.html"},
+		{&tmpls.source, "source.html"},
+	} {
+		tmpl, err := srv.parse("layout.html", "partials.html", t.file)
+		if err != nil {
+			return nil, err
+		}
+		*t.dst = tmpl
+	}
+	return &tmpls, nil
+}
+
+// parse parses the given template files with the built-in and custom functions. Files in srv.Templates take precedence over the embedded ones.
+func (srv *Server) parse(fn ...string) (*template.Template, error) {
+	funcs := template.FuncMap{}
+	maps.Copy(funcs, builtinFuncs)
+	maps.Copy(funcs, srv.Funcs)
+
+	tmpl := template.New(fn[0]).Funcs(funcs)
+	for _, name := range fn {
+		content, err := srv.templateFile(name)
+		if err != nil {
+			return nil, err
+		}
+		t := tmpl
+		if name != tmpl.Name() {
+			t = tmpl.New(name)
+		}
+		if _, err := t.Parse(string(content)); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+func (srv *Server) templateFile(name string) ([]byte, error) {
+	if srv.Templates != nil {
+		content, err := fs.ReadFile(srv.Templates, name)
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return fs.ReadFile(files, name)
+}
 
 type layoutData struct {
+	Anonymous       bool // hide auth-only regions
 	AuthHref        string
 	Base            string
+	Breadcrumbs     []breadcrumb // links to the parent dirs
+	Canonical       string       // absolute URL of the preferred page, if any
 	ContainsAuthKey bool
+	Current         string // URL of the displayed file or dir, empty for other pages
+	Footer          template.HTML
+	Lang            string // user interface language
+	LiveReload      string // URL of the live reload WebSocket, empty if not in dev mode
+	LiveSearchDelay int    // milliseconds, negative means no live search
+	NoIndex         bool
+	OpenSearch      string // URL of the OpenSearch description
+	PathSeparator   string // between the dir path and the name in search results
+	RootURL         string
+	Scope           *scopeData // dir which the search can be restricted to
+	Scripts         []string   // URLs of additional scripts, see Server.DirAssets
 	Search          string
+	SearchAPI       string
+	Sidebar         []navNode
+	SiteTitle       string   // title of the root dir
+	Styles          []string // URLs of additional style sheets, see Server.DirAssets
 	Title           string
 }
 
+// listEntry is an entry in a dir listing.
+type listEntry struct {
+	Entry
+	Active bool // current page, or the readme of the current dir
+}
+
+// IsCurrent reports whether url is the URL of the displayed page, or of a readme which is displayed on it.
+func (data layoutData) IsCurrent(url string) bool {
+	return data.Current != "" && canonicalURL(url) == data.Current
+}
+
+// Mark wraps entries for a listing, marking the current page, e.g. {{template "entries" ($.Mark .Entries)}}.
+func (data layoutData) Mark(entries []Entry) []listEntry {
+	var result = make([]listEntry, len(entries))
+	for i, entry := range entries {
+		result[i] = listEntry{
+			Entry:  entry,
+			Active: data.IsCurrent(entry.URL()),
+		}
+	}
+	return result
+}
+
+type scopeData struct {
+	Active bool // search is restricted to the dir
+	Path   string
+	Title  string
+}
+
 type dirData struct {
 	layoutData
-	Dir *Dir
+	Dir        *Dir
+	Entries    []Entry
+	Groups     []letterGroup // Entries grouped by first letter, nil if not grouped
+	Letters    []letterLink
+	Pagination *pagination // nil if all entries are displayed
+	Recent     []*File     // root only
+	ShowAll    string      // URL which lists all entries, empty if Entries are complete
+}
+
+type errorData struct {
+	layoutData
+	Content    template.HTML // replaces message
+	Message    string
+	RequestID  string // for support requests, empty without the RequestID middleware
+	Status     int
+	StatusText string
 }
 
 type fileData struct {
 	layoutData
-	Dir  *Dir // breadcrumbs
+	Dir  *Dir
 	File *File
 }
 
+type hiddenInput struct {
+	Name  string
+	Value string
+}
+
+type loginData struct {
+	layoutData
+	Action string        // requested path, so the user gets there after authentication
+	Hidden []hiddenInput // other query parameters of the request
+}
+
+type sourceData struct {
+	layoutData
+	File   *File
+	Source template.HTML // highlighted markdown
+}
+
 type searchData struct {
 	layoutData
-	Matches   []DocumentMatch
-	RootTitle string
+	Loose   bool
+	Matches []DocumentMatch
 }
@@ -0,0 +1,173 @@
+package markdump
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/wansing/markdump/static"
+)
+
+func TestRootUsesIndexTemplate(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/page.md": "Hello",
+	}, nil)
+	if body := serve(srv, "/").Body.String(); !strings.Contains(body, `class="card h-100`) {
+		t.Error("root page is not rendered with the index template")
+	}
+	if body := serve(srv, "/docs").Body.String(); strings.Contains(body, `class="card h-100`) || !strings.Contains(body, `class="breadcrumb"`) {
+		t.Error("subdir page is not rendered with the dir template")
+	}
+}
+
+func TestSharedPartials(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md":      "Root readme",
+		"docs/readme.md": "Docs readme",
+		"docs/page.md":   "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+	})
+	for _, test := range []struct {
+		target string
+		readme string
+	}{
+		{"/?auth=secret", "Root readme"},
+		{"/docs?auth=secret", "Docs readme"},
+		{"/docs/page?auth=secret", ""},
+	} {
+		body := serve(srv, test.target).Body.String()
+		if !strings.Contains(body, "This URL contains an access key.") {
+			t.Errorf("GET %s: auth alert is missing", test.target)
+		}
+		if test.readme != "" && !strings.Contains(body, test.readme) {
+			t.Errorf("GET %s: readme is missing", test.target)
+		}
+	}
+}
+
+func TestIndexTemplateOverride(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/page.md": "Hello",
+	}, func(srv *Server) {
+		srv.Templates = fstest.MapFS{
+			"index.html": {Data: []byte(`{{define "main"}}custom index of {{.Dir.Title}}{{end}}`)},
+		}
+	})
+	if body := serve(srv, "/").Body.String(); !strings.Contains(body, "custom index of Home") {
+		t.Errorf("root page is not rendered with index.html: %s", body)
+	}
+	if body := serve(srv, "/docs").Body.String(); strings.Contains(body, "custom index") {
+		t.Error("subdir page is rendered with index.html")
+	}
+}
+
+func TestCustomFuncs(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, func(srv *Server) {
+		srv.Funcs = template.FuncMap{"shout": strings.ToUpper}
+		srv.Templates = fstest.MapFS{
+			"file.html": {Data: []byte(`{{define "main"}}<h1>{{shout .File.Title}}</h1>{{end}}`)},
+		}
+	})
+	if body := serve(srv, "/page").Body.String(); !strings.Contains(body, "<h1>PAGE</h1>") {
+		t.Fatalf("custom function is not applied: %s", body)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Time{}, ""},
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5 min ago"},
+		{now.Add(-1 * time.Hour), "1 hour ago"},
+		{now.Add(-3 * 24 * time.Hour), "3 days ago"},
+		{now.Add(-65 * 24 * time.Hour), "2 months ago"},
+		{now.Add(2 * time.Hour), "in 2 hours"},
+		{now.Add(-400 * 24 * time.Hour), "2023-05-12"},
+	} {
+		if got := relativeTime(test.t, now); got != test.want {
+			t.Errorf("relativeTime(%v) = %q, want %q", test.t, got, test.want)
+		}
+	}
+}
+
+func TestLayoutReferencesFingerprintedAssets(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "Hello",
+	}, nil)
+	body := serve(srv, "/").Body.String()
+	if fp := static.Path("style.css"); fp == "/static/style.css" || !strings.Contains(body, `href="`+fp+`"`) {
+		t.Fatalf("page does not reference the fingerprinted style sheet %s", fp)
+	}
+}
+
+func TestActiveEntry(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/a.md":      "A",
+		"docs/b.md":      "B",
+		"docs/readme.md": "Docs",
+	}, func(srv *Server) {
+		srv.SidebarDepth = 1
+	})
+	for _, test := range []struct {
+		target string
+		active string
+	}{
+		{"/docs", "/docs/readme"}, // displayed on the dir page
+		{"/docs/b", "/docs/b"},
+	} {
+		body := serve(srv, test.target).Body.String()
+		if !strings.Contains(body, `<a href="`+test.active+`" class="fw-bold" aria-current="page">`) {
+			t.Errorf("GET %s: %s is not marked as active", test.target, test.active)
+		}
+		if strings.Contains(body, `<a href="/docs/a" class="fw-bold"`) {
+			t.Errorf("GET %s: /docs/a is marked as active", test.target)
+		}
+	}
+}
+
+func TestDirAssets(t *testing.T) {
+	files := map[string]string{
+		"product/style.css":     "h1 { color: teal; }",
+		"product/sub/script.js": "console.log(1);",
+		"product/sub/page.md":   "Hello",
+		"product/other.md":      "Hello",
+		"plain.md":              "Hello",
+	}
+	srv := newTestServer(t, files, func(srv *Server) {
+		srv.DirAssets = true
+	})
+	for _, test := range []struct {
+		target        string
+		style, script bool
+	}{
+		{"/product/sub/page", true, true},
+		{"/product/other", true, false}, // scripts of subdirs do not apply
+		{"/product", true, false},
+		{"/plain", false, false},
+	} {
+		body := serve(srv, test.target).Body.String()
+		if style := strings.Contains(body, `<link href="/product/style.css" rel="stylesheet">`); style != test.style {
+			t.Errorf("GET %s: got style %t, want %t", test.target, style, test.style)
+		}
+		if script := strings.Contains(body, `<script src="/product/sub/script.js" defer></script>`); script != test.script {
+			t.Errorf("GET %s: got script %t, want %t", test.target, script, test.script)
+		}
+	}
+	if body := serve(srv, "/product/style.css").Body.String(); body != files["product/style.css"] {
+		t.Errorf("got style sheet %q", body)
+	}
+
+	srv = newTestServer(t, files, nil)
+	if body := serve(srv, "/product/sub/page").Body.String(); strings.Contains(body, "/product/style.css") {
+		t.Errorf("style is linked without DirAssets")
+	}
+}
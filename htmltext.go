@@ -0,0 +1,20 @@
+package markdump
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlInvisible = regexp.MustCompile(`(?is)<!--.*?-->|<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+	htmlTag       = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// htmlText returns the text content of an HTML fragment, e.g. a rendered markdown file, for the search index. It is not a sanitizer.
+func htmlText(content []byte) string {
+	s := htmlInvisible.ReplaceAllString(string(content), " ")
+	s = htmlTag.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,110 @@
+package markdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// languages of the user interface. The first one is the default. The messages in the templates are English and serve as keys of the catalog.
+var languages = []language.Tag{language.English, language.German}
+
+var languageMatcher = language.NewMatcher(languages)
+
+// catalog contains the translations of the user interface messages per language.
+var catalog = map[string]map[string]string{
+	"de": {
+		"Access Key Required":    "Zugangsschlüssel erforderlich",
+		"Access key":             "Zugangsschlüssel",
+		"Back to the start page": "Zurück zur Startseite",
+		"Bookmark and Share":     "Merken und teilen",
+		"Content truncated.":     "Inhalt gekürzt.",
+		"Continue":               "Weiter",
+		"Download as zip":        "Als ZIP herunterladen",
+		"DRAFT":                  "ENTWURF",
+		"Gateway Timeout":        "Zeitüberschreitung",
+		"Internal Server Error":  "Interner Serverfehler",
+		"Navigation":             "Navigation",
+		"Next":                   "Weiter",
+		"No matches.":            "Keine Treffer.",
+		"No page contains all words. Showing pages which contain some of them.": "Keine Seite enthält alle Wörter. Angezeigt werden Seiten, die einige davon enthalten.",
+		"No search results.":               "Keine Suchergebnisse.",
+		"Not Found":                        "Nicht gefunden",
+		"Page %d of %d":                    "Seite %d von %d",
+		"Previous":                         "Zurück",
+		"Recently Modified":                "Zuletzt geändert",
+		"References":                       "Referenzen",
+		"Request ID: %s":                   "Anfrage-ID: %s",
+		"Search Results":                   "Suchergebnisse",
+		"Search":                           "Suche",
+		"Search: %s":                       "Suche: %s",
+		"Searching within %s.":             "Suche in %s.",
+		"Show all %d entries":              "Alle %d Einträge anzeigen",
+		"Showing the first %d of %d rows.": "Die ersten %d von %d Zeilen werden angezeigt.",
+		"Source":                           "Quelltext",
+		"The requested folder contains no pages.": "Der angeforderte Ordner enthält keine Seiten.",
+		"The requested page does not exist.":      "Die angeforderte Seite existiert nicht.",
+		"The requested path is too long.":         "Der angeforderte Pfad ist zu lang.",
+		"The search failed.":                      "Die Suche ist fehlgeschlagen.",
+		"The search took too long.":               "Die Suche hat zu lange gedauert.",
+		"This page requires an access key. Please use a link which contains one, or enter your access key below.": "Diese Seite erfordert einen Zugangsschlüssel. Bitte verwende einen Link, der einen enthält, oder gib deinen Zugangsschlüssel unten ein.",
+		"This URL contains an access key. You can bookmark or share it.":                                          "Diese URL enthält einen Zugangsschlüssel. Du kannst sie als Lesezeichen speichern oder teilen.",
+		"Unauthorized":         "Nicht autorisiert",
+		"Unprocessable Entity": "Nicht verarbeitbar",
+		"in %s":                "in %s",
+	},
+}
+
+// requestLang returns the user interface language for the request, chosen by the "lang" query parameter or the Accept-Language header.
+func requestLang(r *http.Request) string {
+	_, index := language.MatchStrings(languageMatcher, r.URL.Query().Get("lang"), r.Header.Get("Accept-Language"))
+	return languages[index].String()
+}
+
+// translate returns the translation of msg, or msg itself if there is none, formatted with args, e.g. {{t .Lang "Page %d of %d" .Page .Pages}}.
+func translate(lang, msg string, args ...any) string {
+	if translated, ok := catalog[lang][msg]; ok {
+		msg = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// contentMessageMarker matches a placeholder for a user interface message in rendered content, see contentMessage.
+var contentMessageMarker = regexp.MustCompile(`<!--markdump-msg (\{.*?\})-->`)
+
+type contentMsg struct {
+	Msg  string `json:"msg"`
+	Args []int  `json:"args,omitempty"`
+}
+
+// contentMessage returns a placeholder for a user interface message in rendered content. Files are rendered without a request, so the message is translated by localize when the content is served.
+func contentMessage(msg string, args ...int) template.HTML {
+	encoded, _ := json.Marshal(contentMsg{Msg: msg, Args: args})
+	return template.HTML("<!--markdump-msg " + string(encoded) + "-->")
+}
+
+// localize replaces the message placeholders in content by their translation.
+func localize(lang string, content template.HTML) template.HTML {
+	if !strings.Contains(string(content), "<!--markdump-msg ") {
+		return content
+	}
+	return template.HTML(contentMessageMarker.ReplaceAllStringFunc(string(content), func(marker string) string {
+		var m contentMsg
+		if err := json.Unmarshal([]byte(contentMessageMarker.FindStringSubmatch(marker)[1]), &m); err != nil {
+			return ""
+		}
+		var args = make([]any, len(m.Args))
+		for i, arg := range m.Args {
+			args[i] = arg
+		}
+		return template.HTMLEscapeString(translate(lang, m.Msg, args...))
+	}))
+}
@@ -0,0 +1,36 @@
+package markdump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGermanLocale(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/page.md": "Hello",
+	}, func(srv *Server) {
+		srv.MaxRenderedSize = 1 // truncation notice is a message placeholder in the rendered content
+	})
+	for _, test := range []struct {
+		target  string
+		headers []string
+		want    []string
+		notWant string
+	}{
+		{"/docs", []string{"Accept-Language", "de-DE,de;q=0.9,en;q=0.8"}, []string{`<html lang="de">`, "Als ZIP herunterladen"}, "Download as zip"},
+		{"/docs", []string{"Accept-Language", "fr"}, []string{`<html lang="en">`, "Download as zip"}, "Als ZIP herunterladen"},
+		{"/docs?lang=de", nil, []string{"Als ZIP herunterladen"}, "Download as zip"},
+		{"/docs/page", []string{"Accept-Language", "de"}, []string{"Inhalt gekürzt."}, "Content truncated."},
+		{"/missing", []string{"Accept-Language", "de"}, []string{"Die angeforderte Seite existiert nicht."}, "The requested page does not exist."},
+	} {
+		body := serve(srv, test.target, test.headers...).Body.String()
+		for _, want := range test.want {
+			if !strings.Contains(body, want) {
+				t.Errorf("GET %s %v: body does not contain %q", test.target, test.headers, want)
+			}
+		}
+		if strings.Contains(body, test.notWant) {
+			t.Errorf("GET %s %v: body contains %q", test.target, test.headers, test.notWant)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package markdump
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+type letterGroup struct {
+	Letter  string // "A" to "Z" or "#"
+	Entries []Entry
+}
+
+// ID returns the anchor of the group.
+func (group letterGroup) ID() string {
+	if group.Letter == "#" {
+		return "letter-other"
+	}
+	return "letter-" + strings.ToLower(group.Letter)
+}
+
+type letterLink struct {
+	Letter string
+	ID     string // empty if no entry starts with the letter
+}
+
+// letterGroups groups entries by the first letter of their title. It returns nil if there are not more than srv.LetterIndexThreshold entries or the threshold is zero.
+func (srv *Server) letterGroups(entries []Entry) ([]letterGroup, []letterLink) {
+	if srv.LetterIndexThreshold <= 0 || len(entries) <= srv.LetterIndexThreshold {
+		return nil, nil
+	}
+
+	var groups = map[string]*letterGroup{}
+	for _, entry := range entries {
+		letter := initial(entry.Title())
+		group, ok := groups[letter]
+		if !ok {
+			group = &letterGroup{Letter: letter}
+			groups[letter] = group
+		}
+		group.Entries = append(group.Entries, entry)
+	}
+
+	var result = make([]letterGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Letter == "#" || result[j].Letter == "#" {
+			return result[j].Letter == "#" && result[i].Letter != "#" // "#" goes last
+		}
+		return result[i].Letter < result[j].Letter
+	})
+
+	var links []letterLink
+	for _, letter := range strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ#", "") {
+		link := letterLink{Letter: letter}
+		if group, ok := groups[letter]; ok {
+			link.ID = group.ID()
+		}
+		links = append(links, link)
+	}
+	return result, links
+}
+
+// initial returns the uppercase first letter of title without diacritics, or "#" if it is not in A to Z.
+func initial(title string) string {
+	title, _, _ = transform.String(removeDiacritics(), strings.TrimSpace(title))
+	r, _ := utf8.DecodeRuneInString(title)
+	r = unicode.ToUpper(r)
+	if r < 'A' || r > 'Z' {
+		return "#"
+	}
+	return string(r)
+}
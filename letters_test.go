@@ -0,0 +1,44 @@
+package markdump
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLetterGroups(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"list/apple.md":   "A",
+		"list/avocado.md": "A",
+		"list/cherry.md":  "C",
+		"list/Ölbaum.md":  "O",
+		"list/2024.md":    "#",
+	}, func(srv *Server) {
+		srv.LetterIndexThreshold = 3
+	})
+	groups, links := srv.letterGroups(srv.entries(srv.Root().Subdirs["list"]))
+
+	var letters []string
+	for _, group := range groups {
+		letters = append(letters, group.Letter)
+	}
+	if want := []string{"A", "C", "O", "#"}; !slices.Equal(letters, want) {
+		t.Fatalf("got groups %v, want %v", letters, want)
+	}
+	if len(groups[0].Entries) != 2 {
+		t.Errorf("got %d entries in group A, want 2", len(groups[0].Entries))
+	}
+
+	var linked []string
+	for _, link := range links {
+		if link.ID != "" {
+			linked = append(linked, link.Letter)
+		}
+	}
+	if want := []string{"A", "C", "O", "#"}; !slices.Equal(linked, want) || len(links) != 27 {
+		t.Fatalf("got linked letters %v of %d, want %v of 27", linked, len(links), want)
+	}
+
+	if groups, _ := srv.letterGroups(srv.entries(srv.Root())); groups != nil {
+		t.Error("root with fewer entries than the threshold is grouped")
+	}
+}
@@ -0,0 +1,92 @@
+package markdump
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// websocketGUID is appended to the Sec-WebSocket-Key, see RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// HandleLiveReload accepts WebSocket connections and sends "reload" after each successful Reload. It is only available if srv.DevMode is set, and requires authentication like the content.
+func (srv *Server) HandleLiveReload(w http.ResponseWriter, r *http.Request) {
+	if !srv.DevMode {
+		http.NotFound(w, r)
+		return
+	}
+	if _, authenticated := srv.authenticated(w, r); !authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	accept := sha1.Sum([]byte(key + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	reloaded := srv.subscribeReload()
+	defer srv.unsubscribeReload(reloaded)
+
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, bufio.NewReader(conn)) // client frames are ignored, returns when the connection is closed
+		close(closed)
+	}()
+
+	select {
+	case <-reloaded:
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		conn.Write(websocketTextFrame("reload"))
+	case <-closed:
+	}
+}
+
+// websocketTextFrame returns an unmasked, unfragmented text frame with a payload shorter than 126 bytes.
+func websocketTextFrame(payload string) []byte {
+	return append([]byte{0x81, byte(len(payload))}, payload...)
+}
+
+// subscribeReload returns a channel which is closed after the next successful Reload.
+func (srv *Server) subscribeReload() chan struct{} {
+	srv.reloadSubsMu.Lock()
+	defer srv.reloadSubsMu.Unlock()
+	if srv.reloadSubs == nil {
+		srv.reloadSubs = make(map[chan struct{}]struct{})
+	}
+	ch := make(chan struct{})
+	srv.reloadSubs[ch] = struct{}{}
+	return ch
+}
+
+func (srv *Server) unsubscribeReload(ch chan struct{}) {
+	srv.reloadSubsMu.Lock()
+	defer srv.reloadSubsMu.Unlock()
+	delete(srv.reloadSubs, ch)
+}
+
+// broadcastReload notifies all subscribers.
+func (srv *Server) broadcastReload() {
+	srv.reloadSubsMu.Lock()
+	defer srv.reloadSubsMu.Unlock()
+	for ch := range srv.reloadSubs {
+		close(ch)
+	}
+	srv.reloadSubs = nil
+}
@@ -0,0 +1,96 @@
+package markdump
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveReload(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, func(srv *Server) {
+		srv.DevMode = true
+	})
+	if body := serve(srv, "/page").Body.String(); !strings.Contains(body, `data-url="/livereload"`) {
+		t.Fatalf("live reload script is not injected: %s", body)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(srv.HandleLiveReload))
+	defer ts.Close()
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "GET /livereload HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("got status %d, accept %q", resp.StatusCode, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	// the handler subscribes after the handshake
+	for {
+		srv.reloadSubsMu.Lock()
+		subscribed := len(srv.reloadSubs) > 0
+		srv.reloadSubsMu.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	frame, err := io.ReadAll(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\x81\x06reload"; string(frame) != want {
+		t.Fatalf("got frame %q, want %q", frame, want)
+	}
+}
+
+func TestLiveReloadAuth(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+		srv.DevMode = true
+	})
+	ts := httptest.NewServer(http.HandlerFunc(srv.HandleLiveReload))
+	defer ts.Close()
+	for _, test := range []struct {
+		cookie string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"auth=wrong", http.StatusUnauthorized},
+		{"auth=secret", http.StatusSwitchingProtocols},
+	} {
+		conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		io.WriteString(conn, "GET /livereload HTTP/1.1\r\nHost: localhost\r\nCookie: "+test.cookie+"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.status {
+			t.Errorf("cookie %q: got status %d, want %d", test.cookie, resp.StatusCode, test.status)
+		}
+	}
+}
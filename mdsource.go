@@ -0,0 +1,76 @@
+package markdump
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFence    = regexp.MustCompile("^ {0,3}(```|~~~)")
+	mdHeading  = regexp.MustCompile(`^ {0,3}#{1,6}(\s|$)`)
+	mdInline   = regexp.MustCompile("`[^`]+`|!?\\[[^\\]]*\\]\\([^)]*\\)")
+	mdListItem = regexp.MustCompile(`^\s*([-*+]|\d{1,9}[.)])\s`)
+	mdQuote    = regexp.MustCompile(`^ {0,3}>`)
+)
+
+// highlightMarkdown returns the escaped markdown source with span elements around the syntax elements, for the source view. It works line by line and is not a full markdown parser.
+//
+// Classes: md-code (fenced code and code spans), md-heading, md-quote, md-list (list markers), md-link (links and images).
+func highlightMarkdown(source []byte) template.HTML {
+	var b strings.Builder
+	lines := strings.SplitAfter(string(source), "\n")
+	var fence string // opening fence while inside a fenced code block
+	for _, line := range lines {
+		text := strings.TrimRight(line, "\r\n")
+		switch {
+		case fence != "":
+			writeSpan(&b, "md-code", text)
+			if strings.HasPrefix(strings.TrimSpace(text), fence) {
+				fence = ""
+			}
+		case mdFence.MatchString(text):
+			writeSpan(&b, "md-code", text)
+			fence = mdFence.FindStringSubmatch(text)[1]
+		case mdHeading.MatchString(text):
+			writeSpan(&b, "md-heading", text)
+		case mdQuote.MatchString(text):
+			writeSpan(&b, "md-quote", text)
+		default:
+			rest := text
+			if m := mdListItem.FindStringSubmatchIndex(text); m != nil {
+				b.WriteString(html.EscapeString(text[:m[2]]))
+				writeSpan(&b, "md-list", text[m[2]:m[3]])
+				rest = text[m[3]:]
+			}
+			writeInline(&b, rest)
+		}
+		b.WriteString(line[len(text):]) // line break
+	}
+	return template.HTML(b.String())
+}
+
+// writeInline writes the escaped text with code spans, links and images highlighted.
+func writeInline(b *strings.Builder, text string) {
+	var offset int
+	for _, m := range mdInline.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[offset:m[0]]))
+		if text[m[0]] == '`' {
+			writeSpan(b, "md-code", text[m[0]:m[1]])
+		} else {
+			writeSpan(b, "md-link", text[m[0]:m[1]])
+		}
+		offset = m[1]
+	}
+	b.WriteString(html.EscapeString(text[offset:]))
+}
+
+func writeSpan(b *strings.Builder, class, text string) {
+	if text == "" {
+		return
+	}
+	b.WriteString(`<span class="` + class + `">`)
+	b.WriteString(html.EscapeString(text))
+	b.WriteString(`</span>`)
+}
@@ -0,0 +1,32 @@
+package markdump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightMarkdown(t *testing.T) {
+	got := string(highlightMarkdown([]byte("# Title\n\n- item with `code` & [link](https://example.com)\n<script>alert(1)</script>\n\n```\n# not a heading\n```\n")))
+	want := `<span class="md-heading"># Title</span>
+
+<span class="md-list">-</span> item with <span class="md-code">` + "`code`" + `</span> &amp; <span class="md-link">[link](https://example.com)</span>
+&lt;script&gt;alert(1)&lt;/script&gt;
+
+<span class="md-code">` + "```" + `</span>
+<span class="md-code"># not a heading</span>
+<span class="md-code">` + "```" + `</span>
+`
+	if got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSourceView(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "# Title\n\nSome <b>bold</b> text.",
+	}, nil)
+	body := serve(srv, "/page?source=1").Body.String()
+	if !strings.Contains(body, `<span class="md-heading"># Title</span>`) || !strings.Contains(body, "Some &lt;b&gt;bold&lt;/b&gt; text.") {
+		t.Fatalf("source view does not show the escaped, highlighted markdown: %s", body)
+	}
+}
@@ -0,0 +1,87 @@
+package markdump
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type mediaRange struct {
+	typ     string // lower case, like "text/html", "text/*" or "*/*"
+	quality float64
+}
+
+func parseAccept(r *http.Request) []mediaRange {
+	var ranges []mediaRange
+	for _, accept := range r.Header.Values("Accept") {
+		for _, item := range strings.Split(accept, ",") {
+			typ, params, _ := strings.Cut(item, ";")
+			var mr = mediaRange{
+				typ:     strings.ToLower(strings.TrimSpace(typ)),
+				quality: 1,
+			}
+			for _, param := range strings.Split(params, ";") {
+				if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+					if quality, err := strconv.ParseFloat(q, 64); err == nil {
+						mr.quality = quality
+					}
+				}
+			}
+			if mr.typ != "" {
+				ranges = append(ranges, mr)
+			}
+		}
+	}
+	return ranges
+}
+
+// accepts returns whether the Accept header of the request contains the given media type explicitly and with a non-zero quality.
+func accepts(r *http.Request, mimeType string) bool {
+	for _, mr := range parseAccept(r) {
+		if mr.typ == mimeType {
+			return mr.quality > 0
+		}
+	}
+	return false
+}
+
+// negotiate returns the offered media type which the client prefers. The first offer is the default, which is returned if the request has no Accept header or if the preferences are equal.
+func negotiate(r *http.Request, offers ...string) string {
+	ranges := parseAccept(r)
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	var best string
+	var bestQuality float64
+	for _, offer := range offers {
+		major, _, _ := strings.Cut(offer, "/")
+		var quality float64
+		var specificity = -1 // exact match takes precedence over wildcards
+		for _, mr := range ranges {
+			var s int
+			switch mr.typ {
+			case offer:
+				s = 2
+			case major + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				specificity = s
+				quality = mr.quality
+			}
+		}
+		if quality > bestQuality {
+			best = offer
+			bestQuality = quality
+		}
+	}
+	if best == "" {
+		return offers[0]
+	}
+	return best
+}
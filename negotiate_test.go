@@ -0,0 +1,42 @@
+package markdump
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPageNegotiation(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "# Heading\n\nSome *text*.",
+	}, nil)
+	for _, test := range []struct {
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"", "text/html; charset=utf-8", "<em>text</em>"},
+		{"text/html,application/xhtml+xml,*/*;q=0.8", "text/html; charset=utf-8", "<em>text</em>"},
+		{"text/markdown", "text/markdown; charset=utf-8", "Some *text*."},
+		{"application/json", "application/json; charset=utf-8", `"html":`},
+	} {
+		w := serve(srv, "/page", "Accept", test.accept)
+		if contentType := w.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("Accept %q: got Content-Type %q, want %q", test.accept, contentType, test.contentType)
+		}
+		if body := w.Body.String(); !strings.Contains(body, test.body) {
+			t.Errorf("Accept %q: body %q does not contain %q", test.accept, body, test.body)
+		}
+	}
+
+	var page struct {
+		Title string `json:"title"`
+		HTML  string `json:"html"`
+	}
+	if err := json.Unmarshal(serve(srv, "/page", "Accept", "application/json").Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "page" || !strings.Contains(page.HTML, "<em>text</em>") || strings.Contains(page.HTML, "<html") {
+		t.Fatalf("got %+v, want the title and the content without layout", page)
+	}
+}
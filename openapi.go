@@ -0,0 +1,125 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// HandleOpenAPI serves an OpenAPI description of the search API. The response schema is derived from DocumentMatch.
+func (srv *Server) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !methodGet(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(srv.openAPI())
+}
+
+func (srv *Server) openAPI() map[string]any {
+	matches := map[string]any{
+		"type":  "array",
+		"items": jsonSchema(reflect.TypeOf(DocumentMatch{})),
+	}
+	facets := jsonSchema(reflect.TypeOf(map[string]uint64{}))
+	parameter := func(name, description string, schema map[string]any) map[string]any {
+		return map[string]any{
+			"name":        name,
+			"in":          "query",
+			"description": description,
+			"schema":      schema,
+		}
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   srv.RootTitle + " search",
+			"version": "1",
+		},
+		"servers": []any{
+			map[string]any{"url": srv.rootURL()},
+		},
+		"paths": map[string]any{
+			"/search": map[string]any{
+				"get": map[string]any{
+					"summary": "Search pages and folders",
+					"parameters": []any{
+						parameter("s", "search input, up to four words are used", map[string]any{"type": "string"}),
+						parameter("fields", "comma-separated stored fields to include: "+strings.Join(storedFields, ", "), map[string]any{"type": "string"}),
+						parameter("facets", `"1" to return the number of matches per section along with the matches`, map[string]any{"type": "string", "enum": []string{"1"}}),
+						parameter("in", "path of a folder to search in", map[string]any{"type": "string"}),
+					},
+					"responses": map[string]any{
+						"200": map[string]any{
+							"description": "matches ordered by score, wrapped in an object if facets are requested",
+							"content": map[string]any{
+								"application/json": map[string]any{
+									"schema": map[string]any{
+										"oneOf": []any{
+											matches,
+											map[string]any{
+												"type": "object",
+												"properties": map[string]any{
+													"matches": matches,
+													"facets":  facets,
+												},
+												"required": []string{"matches", "facets"},
+											},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]any{"description": "unknown field"},
+						"401": map[string]any{"description": "unauthorized"},
+						"500": map[string]any{"description": "search failed"},
+						"504": map[string]any{"description": "search timed out"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// jsonSchema returns a JSON schema of the JSON encoding of t. It supports the types used in the API only.
+func jsonSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": jsonSchema(t.Elem())}
+	case reflect.Pointer:
+		return jsonSchema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchema(t.Elem())}
+	case reflect.Struct:
+		var properties = map[string]any{}
+		var required = []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = jsonSchema(field.Type)
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,42 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	srv := &Server{RootTitle: "Docs", Prefix: "/docs/"}
+	var description struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]struct {
+			Get struct {
+				Parameters []struct {
+					Name string `json:"name"`
+				} `json:"parameters"`
+			} `json:"get"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleOpenAPI), "/openapi.json").Body.Bytes(), &description); err != nil {
+		t.Fatalf("served description is not valid JSON: %v", err)
+	}
+	if description.OpenAPI == "" || len(description.Servers) != 1 || description.Servers[0].URL != "/docs" {
+		t.Fatalf("got %+v", description)
+	}
+	search, ok := description.Paths["/search"]
+	if !ok {
+		t.Fatal("/search path is missing")
+	}
+	var names []string
+	for _, parameter := range search.Get.Parameters {
+		names = append(names, parameter.Name)
+	}
+	if !slices.Contains(names, "s") {
+		t.Fatalf("got parameters %v, want s", names)
+	}
+}
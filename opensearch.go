@@ -0,0 +1,52 @@
+package markdump
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+type openSearchDescription struct {
+	XMLName       xml.Name      `xml:"http://a9.com/-/spec/opensearch/1.1/ OpenSearchDescription"`
+	ShortName     string        `xml:"ShortName"`
+	Description   string        `xml:"Description"`
+	InputEncoding string        `xml:"InputEncoding"`
+	URL           openSearchURL `xml:"Url"`
+}
+
+// HandleOpenSearch serves an OpenSearch description, so browsers can add the search. It does not require authentication, as it contains nothing but the title and the search URL.
+func (srv *Server) HandleOpenSearch(w http.ResponseWriter, r *http.Request) {
+	if !methodGet(w, r) {
+		return
+	}
+	searchURL := srv.origin(r) + strings.TrimSuffix(srv.rootURL(), "/") + "/?s={searchTerms}"
+	w.Header().Set("Content-Type", "application/opensearchdescription+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(openSearchDescription{
+		ShortName:     srv.RootTitle,
+		Description:   "Search " + srv.RootTitle,
+		InputEncoding: "UTF-8",
+		URL: openSearchURL{
+			Type:     "text/html",
+			Template: searchURL,
+		},
+	})
+}
+
+// origin returns the scheme and host of srv.BaseURL, or of the request if srv.BaseURL is not set.
+func (srv *Server) origin(r *http.Request) string {
+	if u, err := url.Parse(srv.BaseURL); err == nil && u.Scheme != "" && u.Host != "" {
+		return u.Scheme + "://" + u.Host
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,38 @@
+package markdump
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenSearch(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+		srv.BaseURL = "https://wiki.example.com/"
+		srv.Prefix = "/docs/"
+	})
+
+	w := serve(http.HandlerFunc(srv.HandleOpenSearch), "/docs/opensearch.xml")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d without authentication", w.Code)
+	}
+	var desc openSearchDescription
+	if err := xml.Unmarshal(w.Body.Bytes(), &desc); err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://wiki.example.com/docs/?s={searchTerms}"; desc.URL.Template != want {
+		t.Errorf("got template %q, want %q", desc.URL.Template, want)
+	}
+	if desc.ShortName != "Home" {
+		t.Errorf("got short name %q, want the root title", desc.ShortName)
+	}
+
+	body := serve(srv, "/docs/page?auth=secret").Body.String()
+	if !strings.Contains(body, `<link rel="search" type="application/opensearchdescription+xml" title="Home" href="/docs/opensearch.xml">`) {
+		t.Errorf("layout does not link the description: %s", body)
+	}
+}
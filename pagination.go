@@ -0,0 +1,56 @@
+package markdump
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type pagination struct {
+	Page  int
+	Pages int
+	Prev  string // URL, empty on first page
+	Next  string // URL, empty on last page
+}
+
+// paginate returns the entries on the requested page. The page size is taken from the "per" query parameter or srv.PageSize. If both are zero, all entries are returned and the pagination is nil.
+func (srv *Server) paginate(r *http.Request, entries []Entry) ([]Entry, *pagination) {
+	query := r.URL.Query()
+	per := srv.PageSize
+	if n, err := strconv.Atoi(query.Get("per")); err == nil && n > 0 {
+		per = min(n, 1000)
+	}
+	if per <= 0 || len(entries) <= per {
+		return entries, nil
+	}
+
+	pages := (len(entries) + per - 1) / per
+	page, _ := strconv.Atoi(query.Get("page"))
+	page = max(1, min(page, pages))
+
+	link := func(page int) string {
+		query.Set("page", strconv.Itoa(page)) // keeps other parameters like auth and per
+		return (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()
+	}
+	p := &pagination{
+		Page:  page,
+		Pages: pages,
+	}
+	if page > 1 {
+		p.Prev = link(page - 1)
+	}
+	if page < pages {
+		p.Next = link(page + 1)
+	}
+	return entries[(page-1)*per : min(page*per, len(entries))], p
+}
+
+// limitEntries returns the first srv.ListingLimit entries and a URL which lists all of them, unless the "all" query parameter is set. If the entries are not truncated, the URL is empty.
+func (srv *Server) limitEntries(r *http.Request, entries []Entry) ([]Entry, string) {
+	query := r.URL.Query()
+	if srv.ListingLimit <= 0 || len(entries) <= srv.ListingLimit || query.Get("all") == "1" {
+		return entries, ""
+	}
+	query.Set("all", "1") // keeps other parameters like auth
+	return entries[:srv.ListingLimit], (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()
+}
@@ -0,0 +1,55 @@
+package markdump
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPagination(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"list/a.md": "A",
+		"list/b.md": "B",
+		"list/c.md": "C",
+		"list/d.md": "D",
+		"list/e.md": "E",
+	}, func(srv *Server) {
+		srv.PageSize = 2
+	})
+	body := serve(srv, "/list?page=2").Body.String()
+	for _, want := range []string{
+		`href="/list/c"`,
+		`href="/list/d"`,
+		`href="/list?page=1"`,
+		`href="/list?page=3"`,
+		"Page 2 of 3",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page 2 does not contain %s", want)
+		}
+	}
+	for _, other := range []string{`href="/list/a"`, `href="/list/b"`, `href="/list/e"`} {
+		if strings.Contains(body, other) {
+			t.Errorf("page 2 contains %s", other)
+		}
+	}
+}
+
+func TestListingLimit(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"list/a.md": "A",
+		"list/b.md": "B",
+		"list/c.md": "C",
+	}, func(srv *Server) {
+		srv.ListingLimit = 2
+	})
+	body := serve(srv, "/list").Body.String()
+	if !strings.Contains(body, `href="/list/b"`) || strings.Contains(body, `href="/list/c"`) {
+		t.Fatalf("listing is not truncated: %s", body)
+	}
+	if !strings.Contains(body, `<a href="/list?all=1">Show all 3 entries</a>`) {
+		t.Fatalf("show all link is missing: %s", body)
+	}
+	if body := serve(srv, "/list?all=1").Body.String(); !strings.Contains(body, `href="/list/c"`) || strings.Contains(body, "Show all") {
+		t.Fatalf("show all link does not reveal the rest: %s", body)
+	}
+}
@@ -0,0 +1,71 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRecentPages = 20
+	maxRecentPages     = 100
+)
+
+type recentPage struct {
+	Title    string    `json:"title"`
+	URL      string    `json:"url"`
+	Modified time.Time `json:"modified"`
+}
+
+// HandleRecent returns the most recently modified pages as a JSON array, most recent first. The "n" query parameter sets the number of pages, the "in" query parameter restricts them to a dir like in search.
+func (srv *Server) HandleRecent(w http.ResponseWriter, r *http.Request) {
+	srv.noIndex(w)
+	if !methodGet(w, r) || !srv.uriLengthOK(w, r) || !srv.loaded(w, false) {
+		return
+	}
+
+	_, authenticated := srv.authenticated(w, r)
+	if !authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	n := defaultRecentPages
+	if s := r.URL.Query().Get("n"); s != "" {
+		var err error
+		if n, err = strconv.Atoi(s); err != nil || n < 1 {
+			http.Error(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = min(n, maxRecentPages)
+	}
+
+	var prefix string
+	if in := r.URL.Query().Get("in"); in != "" {
+		scope := srv.searchScope(in)
+		if scope == nil {
+			http.Error(w, "dir not found", http.StatusNotFound)
+			return
+		}
+		prefix = strings.TrimSuffix(scope.url, "/") + "/"
+	}
+
+	var pages = []recentPage{} // encode "no pages" as empty array, not null
+	for _, file := range srv.current.Load().pages {
+		if len(pages) >= n {
+			break
+		}
+		if !strings.HasPrefix(file.url, prefix) {
+			continue
+		}
+		pages = append(pages, recentPage{
+			Title:    file.title,
+			URL:      file.url,
+			Modified: file.ModTime,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(pages)
+}
@@ -0,0 +1,53 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecent(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"old.md":        "Old",
+		"new.md":        "New",
+		"ops/middle.md": "Middle",
+		"ops/oldest.md": "Oldest",
+		"ops/readme.md": "Ops", // readmes are not listed
+	}, nil)
+	now := time.Now().Truncate(time.Second)
+	setModTimes(t, srv, map[string]time.Time{
+		"new.md":        now,
+		"ops/readme.md": now.Add(time.Minute),
+		"ops/middle.md": now.Add(-time.Hour),
+		"old.md":        now.Add(-2 * time.Hour),
+		"ops/oldest.md": now.Add(-3 * time.Hour),
+	})
+	for _, test := range []struct {
+		target string
+		want   []string
+	}{
+		{"/recent.json", []string{"/new", "/ops/middle", "/old", "/ops/oldest"}},
+		{"/recent.json?n=2", []string{"/new", "/ops/middle"}},
+		{"/recent.json?in=/ops", []string{"/ops/middle", "/ops/oldest"}},
+	} {
+		var pages []recentPage
+		if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleRecent), test.target).Body.Bytes(), &pages); err != nil {
+			t.Fatal(err)
+		}
+		var urls []string
+		for i, page := range pages {
+			urls = append(urls, page.URL)
+			if i > 0 && page.Modified.After(pages[i-1].Modified) {
+				t.Errorf("GET %s: %s is more recent than %s", test.target, page.URL, pages[i-1].URL)
+			}
+		}
+		if !slices.Equal(urls, test.want) {
+			t.Errorf("GET %s: got %v, want %v", test.target, urls, test.want)
+		}
+	}
+	if code := serve(http.HandlerFunc(srv.HandleRecent), "/recent.json?n=0").Code; code != http.StatusBadRequest {
+		t.Errorf("invalid n: got status %d", code)
+	}
+}
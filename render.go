@@ -0,0 +1,246 @@
+package markdump
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// render renders the markdown of the file at fsPath to HTML, applying the optional post-processing steps of srv. The content folder fsDir is required for includes. Snippets included from srv.SharedDir are rendered once and stored in shared, if it is not nil.
+func (srv *Server) render(fsDir, fsPath string, mdContent []byte, shared map[string]template.HTML) template.HTML {
+	return srv.renderFile(fsDir, fsPath, mdContent, nil, shared)
+}
+
+// renderFile is like render. The stack contains the paths of the including files.
+func (srv *Server) renderFile(fsDir, fsPath string, mdContent []byte, stack []string, shared map[string]template.HTML) template.HTML {
+	var includes []template.HTML
+	mdContent = replaceLines(mdContent, includeDirective, func(m [][]byte) []byte {
+		includes = append(includes, srv.include(fsDir, fsPath, string(m[1]), append(slices.Clip(stack), fsPath), shared))
+		return []byte(fmt.Sprintf("<!--markdump-include-%d-->", len(includes)-1))
+	})
+
+	var details []string
+	if srv.Details {
+		mdContent, details = renderDetails(mdContent)
+	}
+
+	var buf strings.Builder
+	buf.WriteString(md.RenderToString(mdContent))
+	if srv.References {
+		if refs := references(stripAuthOnly(mdContent)); len(refs) > 0 {
+			if err := referencesTmpl.Execute(&buf, struct {
+				Heading template.HTML
+				Refs    []reference
+			}{contentMessage("References"), refs}); err != nil {
+				log.Printf("error rendering references: %v", err)
+			}
+		}
+	}
+
+	html := buf.String()
+	for i, block := range details {
+		html = strings.Replace(html, detailsPlaceholder(i), block, 1)
+	}
+	for i, include := range includes {
+		html = strings.Replace(html, fmt.Sprintf("<!--markdump-include-%d-->", i), string(include), 1)
+	}
+	if srv.StripComments {
+		html = stripComments(html)
+	}
+	if srv.DefinitionLists {
+		html = renderDefinitionLists(html)
+	}
+	if srv.Admonitions {
+		html = renderAdmonitions(html)
+	}
+	if srv.Figures {
+		html = wrapFigures(html)
+	}
+	if srv.ExternalLinkRel {
+		html = markExternalLinks(html, srv.BaseURL)
+	}
+	return template.HTML(html)
+}
+
+var truncatedNotice = `<div class="alert alert-warning">` + string(contentMessage("Content truncated.")) + `</div>`
+
+// htmlToken matches a comment, a start tag or an end tag. Group 1 is "/" for end tags, group 2 is the tag name.
+var htmlToken = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9-]*)[^>]*>`)
+
+// voidElements have no end tag.
+var voidElements = map[string]bool{"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true}
+
+// truncateHTML shortens content to at most limit bytes and appends a notice. It cuts between top-level elements outside of auth-only regions only, so no element is left unclosed and no auth-only region loses its end marker.
+func truncateHTML(content template.HTML, limit int) template.HTML {
+	s := string(content)
+	var cut int // end of the last top-level element which fits
+	var depth int
+	var authOnly bool
+	for _, loc := range htmlToken.FindAllStringSubmatchIndex(s, -1) {
+		if loc[1] > limit {
+			break
+		}
+		token := s[loc[0]:loc[1]]
+		switch {
+		case strings.HasPrefix(token, "<!--"):
+			if authOnlyMarker.MatchString(token) {
+				authOnly = !strings.Contains(token, "/auth-only")
+			}
+		case loc[3] > loc[2]: // end tag
+			depth = max(0, depth-1)
+		case !voidElements[strings.ToLower(s[loc[4]:loc[5]])] && !strings.HasSuffix(token, "/>"):
+			depth++
+		}
+		if depth == 0 && !authOnly {
+			cut = loc[1]
+		}
+	}
+	return template.HTML(s[:cut] + "\n" + truncatedNotice)
+}
+
+var htmlComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+// stripComments removes HTML comments, except for the markers of auth-only regions and message placeholders.
+func stripComments(html string) string {
+	return htmlComment.ReplaceAllStringFunc(html, func(comment string) string {
+		if authOnlyMarker.MatchString(comment) || contentMessageMarker.MatchString(comment) {
+			return comment
+		}
+		return ""
+	})
+}
+
+// matches a transclusion directive like {{include: shared/warning.md}}
+var includeDirective = regexp.MustCompile(`^\s*\{\{\s*include:\s*(.+?)\s*\}\}\s*$`)
+
+var includeErrorTmpl = template.Must(template.New("include-error").Parse(`<div class="alert alert-danger">Error including {{.Target}}: {{.Message}}</div>`))
+
+// include renders the target file, which is relative to the including file or, if it starts with a slash, to the content folder fsDir. A target like "shared:legal/privacy.md" is relative to srv.SharedDir instead.
+func (srv *Server) include(fsDir, fsPath, target string, stack []string, shared map[string]template.HTML) template.HTML {
+	includeError := func(message string) template.HTML {
+		log.Printf("error including %s in %s: %s", target, fsPath, message)
+		var buf strings.Builder
+		includeErrorTmpl.Execute(&buf, struct{ Target, Message string }{target, message})
+		return template.HTML(buf.String())
+	}
+
+	var targetPath string
+	if name, ok := strings.CutPrefix(target, "shared:"); ok {
+		if srv.SharedDir == "" {
+			return includeError("no shared folder configured")
+		}
+		fsDir = srv.SharedDir // relative includes within the snippet must not leave the shared folder
+		targetPath = filepath.Join(fsDir, filepath.FromSlash(name))
+	} else if strings.HasPrefix(target, "/") {
+		targetPath = filepath.Join(fsDir, filepath.FromSlash(target))
+	} else {
+		targetPath = filepath.Join(filepath.Dir(fsPath), filepath.FromSlash(target))
+	}
+	rel, err := filepath.Rel(fsDir, targetPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return includeError("outside of folder")
+	}
+	for _, segment := range strings.Split(filepath.ToSlash(rel), "/") {
+		if strings.HasPrefix(segment, ".") {
+			return includeError("hidden file")
+		}
+	}
+	if slices.Contains(stack, targetPath) {
+		return includeError("cyclic include")
+	}
+	isShared := srv.SharedDir != "" && fsDir == srv.SharedDir
+	if html, ok := shared[targetPath]; ok && isShared {
+		return html
+	}
+	mdContent, err := os.ReadFile(targetPath)
+	if err != nil {
+		return includeError("file not found")
+	}
+	_, mdContent, _ = splitFrontMatter(mdContent)
+	html := srv.renderFile(fsDir, targetPath, mdContent, stack, shared)
+	if isShared && shared != nil {
+		shared[targetPath] = html
+	}
+	return html
+}
+
+// replaceLines replaces each line outside of fenced code blocks which matches re with the result of fn.
+func replaceLines(src []byte, re *regexp.Regexp, fn func(submatches [][]byte) []byte) []byte {
+	var result = make([]byte, 0, len(src))
+	var fence string
+	for _, line := range bytes.SplitAfter(src, []byte("\n")) {
+		content := bytes.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(string(content))
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```"):
+			fence = "```"
+		case strings.HasPrefix(trimmed, "~~~"):
+			fence = "~~~"
+		default:
+			if m := re.FindSubmatch(content); m != nil {
+				result = append(result, fn(m)...)
+				result = append(result, line[len(content):]...) // line ending
+				continue
+			}
+		}
+		result = append(result, line...)
+	}
+	return result
+}
+
+type reference struct {
+	Label string
+	URL   string
+	Title string
+}
+
+// matches a link reference definition like [label]: https://example.com "Title"
+var referenceDefinition = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:\s*<?([^\s>]+)>?(?:\s+["'(](.*)["')])?\s*$`)
+
+var referencesTmpl = template.Must(template.New("references").Parse(`<section class="references"><h2>{{.Heading}}</h2><ol>{{range .Refs}}<li><a href="{{.URL}}">{{.Label}}</a>{{with .Title}}: {{.}}{{end}}</li>{{end}}</ol></section>`))
+
+// references returns the link reference definitions in mdContent, skipping fenced code blocks.
+func references(mdContent []byte) []reference {
+	var refs []reference
+	var fence string
+	for _, line := range bytes.Split(mdContent, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(string(line))
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") {
+			fence = "```"
+			continue
+		}
+		if strings.HasPrefix(trimmed, "~~~") {
+			fence = "~~~"
+			continue
+		}
+		if m := referenceDefinition.FindSubmatch(line); m != nil {
+			if u, err := url.Parse(string(m[2])); err != nil || !slices.Contains([]string{"", "ftp", "http", "https", "mailto"}, strings.ToLower(u.Scheme)) {
+				continue // the markdown renderer rejects them too
+			}
+			refs = append(refs, reference{
+				Label: string(m[1]),
+				URL:   string(m[2]),
+				Title: string(m[3]),
+			})
+		}
+	}
+	return refs
+}
@@ -0,0 +1,200 @@
+package markdump
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// renderString renders mdContent with the options of srv, without includes.
+func renderString(srv *Server, mdContent string) string {
+	return string(srv.render("", "", []byte(mdContent), nil))
+}
+
+func TestReferences(t *testing.T) {
+	srv := &Server{References: true}
+	html := renderString(srv, "See [Go][go] and [the spec][spec].\n\n[go]: https://go.dev\n[spec]: https://go.dev/ref/spec \"Language Specification\"\n")
+	want := `<ol><li><a href="https://go.dev">go</a></li><li><a href="https://go.dev/ref/spec">spec</a>: Language Specification</li></ol>`
+	if !strings.Contains(html, `<a href="https://go.dev">Go</a>`) || !strings.Contains(html, want) {
+		t.Fatalf("got %s, want the links and a references section", html)
+	}
+	if localized := string(localize("de", template.HTML(html))); !strings.Contains(localized, "<h2>Referenzen</h2>") {
+		t.Fatalf("got %s, want a translated heading", localized)
+	}
+
+	if html := renderString(&Server{}, "See [Go][go].\n\n[go]: https://go.dev\n"); strings.Contains(html, "references") {
+		t.Fatalf("got %s, want no references section by default", html)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md":       "Before\n\n{{include: parts/note.md}}\n\nAfter",
+		"parts/note.md": "Included *text* and\n\n{{include: ../parts/more.md}}",
+		"parts/more.md": "more",
+		"cycle/a.md":    "A\n\n{{include: b.md}}",
+		"cycle/b.md":    "B\n\n{{include: /cycle/a.md}}",
+		"escape.md":     "{{include: ../outside.md}}",
+		"code.md":       "```\n{{include: parts/note.md}}\n```",
+	}, nil)
+	root := srv.Root()
+
+	if html := string(root.Files["page"].HTMLContent); !strings.Contains(html, "<em>text</em>") || !strings.Contains(html, "<p>more</p>") {
+		t.Errorf("include is not rendered: %s", html)
+	}
+	if html := string(root.Subdirs["cycle"].Files["a"].HTMLContent); !strings.Contains(html, "cyclic include") {
+		t.Errorf("cyclic include is not detected: %s", html)
+	}
+	if html := string(root.Files["escape"].HTMLContent); !strings.Contains(html, "outside of folder") {
+		t.Errorf("include outside of the content folder is not rejected: %s", html)
+	}
+	if html := string(root.Files["code"].HTMLContent); !strings.Contains(html, "{{include: parts/note.md}}") {
+		t.Errorf("directive in a code block is replaced: %s", html)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	const mdContent = "Text\n\n<!-- internal note -->\n\n<!-- auth-only -->\nSecret\n<!-- /auth-only -->\n"
+	if html := renderString(&Server{}, mdContent); !strings.Contains(html, "<!-- internal note -->") {
+		t.Fatalf("comment is removed by default: %s", html)
+	}
+	html := renderString(&Server{StripComments: true}, mdContent)
+	if strings.Contains(html, "internal note") {
+		t.Fatalf("comment is not removed: %s", html)
+	}
+	if !strings.Contains(html, "<!-- auth-only -->") || !strings.Contains(html, "<!-- /auth-only -->") {
+		t.Fatalf("auth-only markers are removed: %s", html)
+	}
+}
+
+func TestExternalLinkRel(t *testing.T) {
+	srv := &Server{ExternalLinkRel: true, BaseURL: "https://wiki.example.com/"}
+	html := renderString(srv, "[internal](/docs/page) [same host](https://wiki.example.com/x) [external](https://other.example.org/) [mail](mailto:a@example.com)")
+	for _, want := range []string{
+		`<a href="/docs/page">internal</a>`,
+		`<a href="https://wiki.example.com/x">same host</a>`,
+		`<a href="https://other.example.org/" rel="nofollow ugc noopener" target="_blank">external</a>`,
+		`<a href="mailto:a@example.com">mail</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("got %s, want %s", html, want)
+		}
+	}
+}
+
+func TestFigures(t *testing.T) {
+	srv := &Server{Figures: true}
+	html := renderString(srv, "![A diagram](diagram.png)\n\nText with ![an icon](icon.png) inline.")
+	if !strings.Contains(html, `<figure><img src="diagram.png" alt="A diagram"><figcaption>A diagram</figcaption></figure>`) {
+		t.Errorf("standalone image is not wrapped: %s", html)
+	}
+	if !strings.Contains(html, `<p>Text with <img src="icon.png" alt="an icon"> inline.</p>`) {
+		t.Errorf("inline image is wrapped: %s", html)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	srv := &Server{Details: true}
+	html := renderString(srv, "````details Click me\nSome **bold** text.\n\n```details\nInner\n```\n````\n\n```go\n```details in code\n```")
+	if !strings.Contains(html, "<details><summary>Click me</summary><p>Some <strong>bold</strong> text.</p>\n<details><summary>Details</summary><p>Inner</p>\n</details>\n</details>") {
+		t.Errorf("details block is not rendered: %s", html)
+	}
+	if !strings.Contains(html, "```details in code") {
+		t.Errorf("details fence inside a code block is rendered: %s", html)
+	}
+}
+
+func TestTruncateHTML(t *testing.T) {
+	for _, test := range []struct {
+		content string
+		limit   int
+		want    string
+	}{
+		{"<p>One</p>\n<p>Two</p>\n<p>Three</p>", 24, "<p>One</p>\n<p>Two</p>"},
+		{"<ul>\n<li>One</li>\n<li>Two</li>\n</ul>\n", 20, ""}, // no unclosed list
+		{"<p>A<br>B</p><p>C</p>", 16, "<p>A<br>B</p>"},        // void element
+		{"<p>Public</p>\n<!-- auth-only -->\n<p>Secret</p>\n<!-- /auth-only -->\n<p>More</p>", 50, "<p>Public</p>"},
+		{"<p>Public</p>\n<!-- auth-only -->\n<p>Secret</p>\n<!-- /auth-only -->\n<p>More</p>", 70, "<p>Public</p>\n<!-- auth-only -->\n<p>Secret</p>\n<!-- /auth-only -->"},
+	} {
+		want := test.want + "\n" + truncatedNotice
+		if got := string(truncateHTML(template.HTML(test.content), test.limit)); got != want {
+			t.Errorf("truncateHTML(%q, %d): got %q, want %q", test.content, test.limit, got, want)
+		}
+	}
+
+	srv := newTestServer(t, map[string]string{
+		"long.md": "First paragraph.\n\n" + strings.Repeat("More text. ", 100),
+	}, func(srv *Server) {
+		srv.MaxRenderedSize = 100
+	})
+	body := serve(srv, "/long").Body.String()
+	if !strings.Contains(body, "<p>First paragraph.</p>") || strings.Contains(body, "More text.") || !strings.Contains(body, "Content truncated.") {
+		t.Errorf("long file is not truncated: %s", body)
+	}
+}
+
+func TestAdmonitions(t *testing.T) {
+	html := renderString(&Server{Admonitions: true}, "> [!NOTE]\n> Useful *info*.\n\n> Just a quote.\n\n> [!FOO]\n> Unknown type.")
+	for _, want := range []string{
+		"<div class=\"alert admonition admonition-note alert-info\" role=\"note\"><p class=\"admonition-title\">Note</p>\n<p>Useful <em>info</em>.</p>\n</div>",
+		"<blockquote>\n<p>Just a quote.</p>\n</blockquote>",
+		"<blockquote>\n<p>[!FOO]\nUnknown type.</p>\n</blockquote>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("got %s, want %s", html, want)
+		}
+	}
+}
+
+func TestIncludeShared(t *testing.T) {
+	sharedDir := writeTree(t, map[string]string{
+		"legal/privacy.md": "Privacy policy, version one.",
+	})
+	srv := newTestServer(t, map[string]string{
+		"a.md":      "{{include: shared:legal/privacy.md}}",
+		"b.md":      "{{include: shared:legal/privacy.md}}",
+		"escape.md": "{{include: shared:../outside.md}}",
+	}, func(srv *Server) {
+		srv.SharedDir = sharedDir
+	})
+	if html := string(srv.Root().Files["b"].HTMLContent); !strings.Contains(html, "version one") {
+		t.Fatalf("shared snippet is not included: %s", html)
+	}
+	if html := string(srv.Root().Files["escape"].HTMLContent); !strings.Contains(html, "outside of folder") {
+		t.Errorf("include outside of the shared folder is not rejected: %s", html)
+	}
+	version := serve(srv, "/a").Header().Get("X-Content-Version")
+
+	if err := os.WriteFile(filepath.Join(sharedDir, "legal/privacy.md"), []byte("Privacy policy, version two."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if html := string(srv.Root().Files[name].HTMLContent); !strings.Contains(html, "version two") {
+			t.Errorf("%s: shared snippet is not updated: %s", name, html)
+		}
+	}
+	if got := serve(srv, "/a").Header().Get("X-Content-Version"); got == version {
+		t.Errorf("version has not changed")
+	}
+}
+
+func TestDefinitionLists(t *testing.T) {
+	mdContent := "API\n: Application *programming* interface\n\nSDK\n: Software development kit\n\n```\nTerm\n: not a definition\n```"
+	html := renderString(&Server{DefinitionLists: true}, mdContent)
+	for _, want := range []string{
+		"<dl>\n<dt>API</dt>\n<dd>Application <em>programming</em> interface</dd>\n<dt>SDK</dt>\n<dd>Software development kit</dd>\n</dl>",
+		"<pre><code>Term\n: not a definition\n</code></pre>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("got %s, want %s", html, want)
+		}
+	}
+	if html := renderString(&Server{}, mdContent); strings.Contains(html, "<dl>") {
+		t.Errorf("definition list is rendered without DefinitionLists: %s", html)
+	}
+}
@@ -0,0 +1,39 @@
+package markdump
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, nil)
+	handler := RequestID(srv)
+
+	w := serve(handler, "/missing", "X-Request-Id", "abc-123")
+	if got := w.Header().Get("X-Request-Id"); got != "abc-123" {
+		t.Fatalf("got header %q, want the provided ID", got)
+	}
+	if !strings.Contains(w.Body.String(), "abc-123") {
+		t.Errorf("error page does not show the request ID")
+	}
+	if !strings.Contains(buf.String(), `request_id=abc-123 method=GET path="/missing" status=404`) {
+		t.Errorf("got log %q, want the request ID", buf.String())
+	}
+
+	w = serve(handler, "/page", "X-Request-Id", "bad\nid")
+	if got := w.Header().Get("X-Request-Id"); got == "" || strings.Contains(got, "bad") {
+		t.Errorf("invalid ID: got header %q, want a generated one", got)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("got status %d", w.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package markdump
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// HandleRobots serves robots.txt according to srv.RobotsPolicy. It references the sitemap if srv.BaseURL is set. It does not require authentication.
+func (srv *Server) HandleRobots(w http.ResponseWriter, r *http.Request) {
+	if !methodGet(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(srv.robots()))
+}
+
+func (srv *Server) robots() string {
+	policy := srv.RobotsPolicy
+	if policy == "" {
+		if slices.Contains(srv.authTokens(), "public") {
+			policy = "allow-all"
+		} else {
+			policy = "disallow-all"
+		}
+	}
+	var robots string
+	switch policy {
+	case "allow-all":
+		robots = "User-agent: *\nDisallow:\n"
+	case "disallow-all":
+		return "User-agent: *\nDisallow: /\n"
+	default:
+		robots = policy
+		if !strings.HasSuffix(robots, "\n") {
+			robots += "\n"
+		}
+	}
+	if sitemap := srv.sitemapLink(); sitemap != "" {
+		robots += "Sitemap: " + sitemap + "\n"
+	}
+	return robots
+}
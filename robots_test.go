@@ -0,0 +1,38 @@
+package markdump
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRobots(t *testing.T) {
+	for _, test := range []struct {
+		authTokens []string
+		policy     string
+		baseURL    string
+		want       string
+	}{
+		{[]string{"public"}, "", "", "User-agent: *\nDisallow:\n"},
+		{[]string{"secret"}, "", "", "User-agent: *\nDisallow: /\n"},
+		{[]string{"public"}, "allow-all", "", "User-agent: *\nDisallow:\n"},
+		{[]string{"public"}, "disallow-all", "", "User-agent: *\nDisallow: /\n"},
+		{[]string{"secret"}, "User-agent: *\nDisallow: /private/", "", "User-agent: *\nDisallow: /private/\n"},
+		{[]string{"public"}, "", "https://wiki.example.com/", "User-agent: *\nDisallow:\nSitemap: https://wiki.example.com/sitemap.xml\n"},
+		{[]string{"public"}, "User-agent: *\nDisallow: /drafts/", "https://wiki.example.com", "User-agent: *\nDisallow: /drafts/\nSitemap: https://wiki.example.com/sitemap.xml\n"},
+		{[]string{"public"}, "disallow-all", "https://wiki.example.com/", "User-agent: *\nDisallow: /\n"},
+		{[]string{"secret"}, "allow-all", "https://wiki.example.com/", "User-agent: *\nDisallow:\n"}, // no sitemap for private servers
+	} {
+		srv := &Server{
+			AuthTokens:   test.authTokens,
+			BaseURL:      test.baseURL,
+			RobotsPolicy: test.policy,
+		}
+		w := serve(http.HandlerFunc(srv.HandleRobots), "/robots.txt")
+		if body := w.Body.String(); body != test.want {
+			t.Errorf("policy %q with tokens %v and base URL %q: got %q, want %q", test.policy, test.authTokens, test.baseURL, body, test.want)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+			t.Errorf("got Content-Type %q", contentType)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+)
+
+// HandleRoutes returns the URLs of all dirs, files and listed attachments as a JSON array, e.g. for checking links.
+func (srv *Server) HandleRoutes(w http.ResponseWriter, r *http.Request) {
+	srv.noIndex(w)
+	if !methodGet(w, r) || !srv.uriLengthOK(w, r) || !srv.loaded(w, false) {
+		return
+	}
+
+	_, authenticated := srv.authenticated(w, r)
+	if !authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	routes := srv.Root().routes([]string{})
+	slices.Sort(routes)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(routes)
+}
+
+// routes appends the URLs of dir and all files, attachments and subdirs in it to dst.
+func (dir *Dir) routes(dst []string) []string {
+	dst = append(dst, dir.url)
+	for _, file := range dir.Files {
+		dst = append(dst, file.url)
+	}
+	for _, attachment := range dir.Attachments {
+		dst = append(dst, attachment.url)
+	}
+	for _, subdir := range dir.Subdirs {
+		dst = subdir.routes(dst)
+	}
+	return dst
+}
@@ -0,0 +1,32 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md":            "Hello",
+		"about.md":             "About",
+		"guide/install.md":     "Install",
+		"guide/advanced/x.md":  "X",
+		"guide/img/logo.png":   "png",
+		"empty/.hidden/one.md": "Hidden",
+	}, nil)
+	var routes []string
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleRoutes), "/routes.json").Body.Bytes(), &routes); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/", "/about", "/guide", "/guide/advanced", "/guide/advanced/x", "/guide/install", "/readme"}
+	if !slices.Equal(routes, want) {
+		t.Fatalf("got %v, want %v", routes, want)
+	}
+	for _, route := range routes {
+		if w := serve(srv, route); w.Code != http.StatusOK {
+			t.Errorf("GET %s: got status %d", route, w.Code)
+		}
+	}
+}
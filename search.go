@@ -0,0 +1,327 @@
+package markdump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func (srv *Server) handleSearchHTML(w http.ResponseWriter, r *http.Request, authHref, search string) {
+	search = strings.TrimSpace(search)
+	scope := srv.searchScope(r.URL.Query().Get("in"))
+	params := searchParams{
+		input:  search,
+		fields: storedFields,
+	}
+	if scope != nil {
+		params.scope = scope.url
+	}
+	ctx, cancel := srv.searchContext(r)
+	defer cancel()
+	matches, err := srv.search(ctx, params)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		srv.serveError(w, r, http.StatusGatewayTimeout, "The search took too long.")
+		return
+	case errors.Is(err, context.Canceled):
+		return // client has gone
+	case err != nil:
+		log.Printf("error searching %q: %v", search, err)
+		srv.serveError(w, r, http.StatusInternalServerError, "The search failed.")
+		return
+	}
+	layout := srv.layoutData(r, authHref, translate(requestLang(r), "Search: %s", search))
+	layout.Breadcrumbs = srv.breadcrumbs([]*Dir{srv.Root()})
+	layout.Search = search
+	if scope != nil {
+		layout.Scope = srv.scopeData(scope)
+		layout.Scope.Active = true
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err = srv.current.Load().tmpl.search.Execute(w, searchData{
+		layoutData: layout,
+		Loose:      len(matches) > 0 && matches[0].Loose,
+		Matches:    matches,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// HandleSearchAPI streams the search result as a JSON array.
+func (srv *Server) HandleSearchAPI(w http.ResponseWriter, r *http.Request) {
+	srv.noIndex(w)
+	if !methodGet(w, r) || !srv.uriLengthOK(w, r) || !srv.loaded(w, false) {
+		return
+	}
+
+	_, authenticated := srv.authenticated(w, r)
+	if !authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	fields, err := srv.searchFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	params := searchParams{
+		input:  r.URL.Query().Get("s"),
+		facets: r.URL.Query().Get("facets") == "1",
+		fields: fields,
+	}
+	if scope := srv.searchScope(r.URL.Query().Get("in")); scope != nil {
+		params.scope = scope.url
+	}
+	ctx, cancel := srv.searchContext(r)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	encoder := json.NewEncoder(w)
+	var count int
+	facets, err := srv.searchEach(ctx, params, func(match DocumentMatch) error {
+		if count == 0 {
+			if params.facets {
+				w.Write([]byte(`{"matches":`))
+			}
+			w.Write([]byte("["))
+		} else {
+			w.Write([]byte(","))
+		}
+		count++
+		return encoder.Encode(match)
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return // client has gone
+		}
+		if count == 0 && errors.Is(err, context.DeadlineExceeded) {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			encoder.Encode(map[string]string{"error": "search timed out"})
+			return
+		}
+		log.Printf("error searching %q: %v", params.input, err)
+		if count == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			encoder.Encode(map[string]string{"error": "search failed"})
+			return
+		}
+		panic(http.ErrAbortHandler) // status has been sent, so abort the response instead of sending incomplete but valid JSON
+	}
+	if count == 0 {
+		if params.facets {
+			w.Write([]byte(`{"matches":`))
+		}
+		w.Write([]byte("[]")) // encode "no results" as empty array, not null
+	} else {
+		w.Write([]byte("]"))
+	}
+	if params.facets {
+		if facets == nil {
+			facets = map[string]uint64{}
+		}
+		w.Write([]byte(`,"facets":`))
+		encoder.Encode(facets)
+		w.Write([]byte("}"))
+	}
+	w.Write([]byte("\n"))
+}
+
+type DocumentMatch struct {
+	Href    template.URL  `json:"href"`
+	Path    string        `json:"path,omitempty"`    // without name, empty in the root dir and if not requested
+	Name    template.HTML `json:"name,omitempty"`    // empty if not requested
+	Content template.HTML `json:"content,omitempty"` // empty for dirs and if not requested
+	Loose   bool          `json:"loose,omitempty"`   // matches only some of the search words
+}
+
+// storedFields are the stored fields which can be included in a DocumentMatch.
+var storedFields = []string{"path", "name", "content"}
+
+// searchFields returns the fields from the given comma-separated list, or the default fields if the list is empty. It returns an error if the list contains a field which is not in storedFields.
+func (srv *Server) searchFields(list string) ([]string, error) {
+	if list == "" {
+		if len(srv.SearchFields) > 0 {
+			return srv.SearchFields, nil
+		}
+		return storedFields, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(storedFields, field) {
+			return nil, fmt.Errorf("unknown field %q, expected %s", field, strings.Join(storedFields, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+type searchParams struct {
+	input  string
+	facets bool     // count matches per section
+	fields []string // stored fields to include in the matches
+	scope  string   // URL of the dir to search in, empty means everywhere
+}
+
+// searchScope returns the dir at the given path relative to the root, or nil if it is the root or does not exist.
+func (srv *Server) searchScope(in string) *Dir {
+	reqpath := splitPath(in)
+	if len(reqpath) == 0 || len(reqpath) > 16 {
+		return nil
+	}
+	dir, reqpath := srv.Root().follow(reqpath)
+	if len(reqpath) > 0 {
+		return nil
+	}
+	return dir
+}
+
+// searchContext returns the request context with srv.SearchTimeout, if set.
+func (srv *Server) searchContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if srv.SearchTimeout > 0 {
+		return context.WithTimeout(r.Context(), srv.SearchTimeout)
+	}
+	return context.WithCancel(r.Context())
+}
+
+func (srv *Server) search(ctx context.Context, params searchParams) ([]DocumentMatch, error) {
+	var matches []DocumentMatch
+	_, err := srv.searchEach(ctx, params, func(match DocumentMatch) error {
+		matches = append(matches, match)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// searchEach calls fn for each match, ordered by score. It stops if fn returns an error. If params.facets is set, it returns the number of matches per section.
+func (srv *Server) searchEach(ctx context.Context, params searchParams, fn func(DocumentMatch) error) (map[string]uint64, error) {
+	input := params.input
+
+	// crop input, lowercase (required for bluge.PrefixQuery and bluge.WildcardQuery, which don't have an analyzer), limit to four words, remove too long words and duplicates, keep order for phrase query
+	if len(input) > 128 {
+		input = input[:128]
+	}
+	input = strings.ToLower(input)
+	words := strings.Fields(input)
+	if len(words) > 4 {
+		words = words[:4]
+	}
+	var terms []string // in input order
+	var short []string // terms shorter than srv.MinSearchTermLength
+	var code []string
+	var links []string
+	for _, word := range words {
+		if len(word) > 32 {
+			continue
+		}
+		if codeTerm, ok := strings.CutPrefix(word, "code:"); ok {
+			if codeTerm != "" && !slices.Contains(code, codeTerm) {
+				code = append(code, codeTerm)
+			}
+			continue
+		}
+		if linkTerm, ok := strings.CutPrefix(word, "link:"); ok {
+			if linkTerm != "" && !slices.Contains(links, linkTerm) {
+				links = append(links, linkTerm)
+			}
+			continue
+		}
+		if utf8.RuneCountInString(word) < srv.MinSearchTermLength {
+			short = append(short, word)
+			continue
+		}
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	if len(terms) == 0 && len(code) == 0 && len(links) == 0 && len(short) > 0 {
+		terms = short[:1] // search for something rather than nothing
+	}
+
+	if len(terms) == 0 && len(code) == 0 && len(links) == 0 {
+		return nil, nil
+	}
+
+	request := SearchRequest{
+		Code:      code,
+		Facets:    params.facets,
+		Fields:    params.fields,
+		Fragments: srv.ContentFragments,
+		Highlight: srv.HighlightStrategy,
+		Links:     links,
+		Scope:     params.scope,
+		Terms:     terms,
+	}
+	if srv.SlowSearchThreshold > 0 {
+		defer func(start time.Time) {
+			if elapsed := time.Since(start); elapsed > srv.SlowSearchThreshold {
+				// log the cropped words only, %q escapes control characters
+				srv.logger().Printf("slow search %q took %s", strings.Join(words, " "), elapsed.Round(time.Millisecond))
+			}
+		}(time.Now())
+	}
+	count, facets, err := srv.searchDeduplicated(ctx, request, fn)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 && len(terms) > 1 && srv.LooseFallback {
+		request.Loose = true
+		_, facets, err = srv.searchDeduplicated(ctx, request, fn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return facets, nil
+}
+
+// searchDeduplicated calls srv.Searcher.Search. It returns the number of matches passed to fn. Unless srv.KeepDuplicateResults is set, it passes only the best match per canonical URL.
+func (srv *Server) searchDeduplicated(ctx context.Context, request SearchRequest, fn func(DocumentMatch) error) (int, map[string]uint64, error) {
+	var count int
+	var seen []string // canonical URLs
+	facets, err := srv.Searcher.Search(ctx, request, func(match DocumentMatch) error {
+		match.Loose = request.Loose
+		if !srv.KeepDuplicateResults {
+			// matches are ordered by score, so keep the first one
+			canonical := canonicalURL(string(match.Href))
+			if slices.Contains(seen, canonical) {
+				return nil
+			}
+			seen = append(seen, canonical)
+		}
+		if srv.ResultURLTransform != nil {
+			match.Href = template.URL(srv.ResultURLTransform(string(match.Href)))
+		}
+		if err := fn(match); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return count, facets, err
+}
+
+// canonicalURL returns the URL of the page which displays the given URL. A readme file is displayed on its dir page.
+func canonicalURL(u string) string {
+	if path.Base(u) == "readme" {
+		return path.Dir(u)
+	}
+	return u
+}
@@ -0,0 +1,533 @@
+package markdump
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingSearcher is a Searcher whose searches fail.
+type failingSearcher struct{}
+
+func (failingSearcher) Index(doc SearchDoc) {}
+
+func (failingSearcher) Reload() error {
+	return nil
+}
+
+func (failingSearcher) Search(ctx context.Context, request SearchRequest, fn func(DocumentMatch) error) (map[string]uint64, error) {
+	return nil, errors.New("index is closed")
+}
+
+// searchHrefs returns the hrefs of the matches for input.
+func searchHrefs(t *testing.T, srv *Server, input string) []string {
+	t.Helper()
+	matches, err := srv.search(context.Background(), searchParams{input: input, fields: storedFields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hrefs []string
+	for _, match := range matches {
+		hrefs = append(hrefs, string(match.Href))
+	}
+	return hrefs
+}
+
+func TestSearchReadmeMatchesDir(t *testing.T) {
+	files := map[string]string{
+		"guide/readme.md":  "The zebrafish lives here.",
+		"guide/install.md": "Run the installer.",
+	}
+	srv := newTestServer(t, files, func(srv *Server) {
+		srv.KeepDuplicateResults = true
+	})
+	if hrefs := searchHrefs(t, srv, "zebrafish"); !slices.Contains(hrefs, "/guide") {
+		t.Fatalf("got %v, want the dir /guide", hrefs)
+	}
+
+	srv = newTestServer(t, files, func(srv *Server) {
+		srv.OmitReadmeResults = true
+	})
+	if hrefs := searchHrefs(t, srv, "zebrafish"); !slices.Equal(hrefs, []string{"/guide"}) {
+		t.Fatalf("got %v, want the dir /guide only", hrefs)
+	}
+}
+
+func TestSearchAPINoMatches(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "Hello",
+	}, nil)
+	w := serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=nothing")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Fatalf("got body %q, want an empty array", body)
+	}
+}
+
+func TestSearchLooseFallback(t *testing.T) {
+	files := map[string]string{
+		"both.md":  "An apple and a banana.",
+		"apple.md": "Just an apple.",
+		"other.md": "Nothing to see.",
+	}
+	srv := newTestServer(t, files, nil)
+	if hrefs := searchHrefs(t, srv, "apple banana durian"); len(hrefs) > 0 {
+		t.Fatalf("without fallback: got %v, want no matches", hrefs)
+	}
+
+	srv = newTestServer(t, files, func(srv *Server) {
+		srv.LooseFallback = true
+	})
+	matches, err := srv.search(context.Background(), searchParams{input: "apple banana durian", fields: storedFields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].Href != "/both" || matches[1].Href != "/apple" {
+		t.Fatalf("with fallback: got %v, want /both and /apple", matches)
+	}
+	if !matches[0].Loose {
+		t.Fatal("with fallback: matches are not marked as loose")
+	}
+}
+
+func TestSearchDeduplicated(t *testing.T) {
+	files := map[string]string{
+		"guide/readme.md":  "The zebrafish lives here.",
+		"guide/install.md": "Run the installer.",
+	}
+	srv := newTestServer(t, files, nil)
+	hrefs := searchHrefs(t, srv, "zebrafish")
+	if len(hrefs) != 1 || canonicalURL(hrefs[0]) != "/guide" {
+		t.Fatalf("got %v, want a single result for /guide", hrefs)
+	}
+
+	srv = newTestServer(t, files, func(srv *Server) {
+		srv.KeepDuplicateResults = true
+	})
+	if hrefs := searchHrefs(t, srv, "zebrafish"); len(hrefs) != 2 {
+		t.Fatalf("with KeepDuplicateResults: got %v, want the dir and the readme", hrefs)
+	}
+}
+
+func TestSearchAPIStreamsJSON(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"one.md":       "Common word here.",
+		"two.md":       "Another common word.",
+		"sub/three.md": "A common one.",
+	}, nil)
+	handler := http.HandlerFunc(srv.HandleSearchAPI)
+
+	want, err := srv.search(context.Background(), searchParams{input: "common", fields: storedFields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []DocumentMatch
+	if err := json.Unmarshal(serve(handler, "/search?s=common").Body.Bytes(), &got); err != nil {
+		t.Fatalf("streamed output is not valid JSON: %v", err)
+	}
+	if len(want) != 3 || !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if body := strings.TrimSpace(serve(handler, "/search?s=nothing").Body.String()); body != "[]" {
+		t.Fatalf("no matches: got %q, want an empty array", body)
+	}
+}
+
+func TestSearchAPIFields(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"notes.md": "Some searchable content.",
+	}, nil)
+	var matches []DocumentMatch
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=searchable&fields=name").Body.Bytes(), &matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	if matches[0].Name != "notes.md" || matches[0].Content != "" || matches[0].Path != "" {
+		t.Fatalf("got %+v, want the name only", matches[0])
+	}
+
+	var raw []map[string]any
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=searchable&fields=name").Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"path", "content"} {
+		if _, ok := raw[0][key]; ok {
+			t.Errorf("unrequested field %q is in the result: %v", key, raw[0])
+		}
+	}
+
+	w := serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=searchable&fields=name,bogus")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown field: got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"error"`) || !strings.Contains(body, "bogus") {
+		t.Errorf("unknown field: got body %q, want a JSON error", body)
+	}
+
+	srv.SearchFields = []string{"name"}
+	var defaultMatches []DocumentMatch
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=searchable").Body.Bytes(), &defaultMatches); err != nil {
+		t.Fatal(err)
+	}
+	if len(defaultMatches) != 1 || defaultMatches[0].Name != "notes.md" || defaultMatches[0].Content != "" {
+		t.Errorf("got %+v, want the default fields of the server", defaultMatches)
+	}
+
+	srv.SearchFields = []string{"name", "title"}
+	if err := srv.Reload(); err == nil {
+		t.Error("Reload accepts unknown search fields")
+	}
+}
+
+func TestSearchAPIFacets(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"ops/deploy.md":  "Deploy the kiwi.",
+		"ops/monitor.md": "Monitor the kiwi.",
+		"dev/build.md":   "Build the kiwi.",
+		"top.md":         "A kiwi at the top.",
+	}, nil)
+	var result struct {
+		Matches []DocumentMatch   `json:"matches"`
+		Facets  map[string]uint64 `json:"facets"`
+	}
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=kiwi&facets=1").Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matches) != 4 {
+		t.Fatalf("got %d matches, want 4", len(result.Matches))
+	}
+	if want := map[string]uint64{"ops": 2, "dev": 1}; !reflect.DeepEqual(result.Facets, want) {
+		t.Fatalf("got facets %v, want %v", result.Facets, want)
+	}
+}
+
+func TestSearchScope(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"ops/deploy.md":      "The heron deploys.",
+		"ops/sub/monitor.md": "The heron monitors.",
+		"dev/build.md":       "The heron builds.",
+		"ops-notes.md":       "The heron takes notes.", // shares the prefix of the scope
+	}, nil)
+	var matches []DocumentMatch
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=heron&in=/ops").Body.Bytes(), &matches); err != nil {
+		t.Fatal(err)
+	}
+	var hrefs []string
+	for _, match := range matches {
+		hrefs = append(hrefs, string(match.Href))
+	}
+	slices.Sort(hrefs)
+	if want := []string{"/ops/deploy", "/ops/sub/monitor"}; !slices.Equal(hrefs, want) {
+		t.Fatalf("got %v, want %v", hrefs, want)
+	}
+}
+
+func TestMaxIndexedDocs(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"a.md": "The common gecko.",
+		"b.md": "The common gecko.",
+		"c.md": "The common gecko.",
+		"d.md": "The common gecko.",
+	}, func(srv *Server) {
+		srv.MaxIndexedDocs = 2
+	})
+	if hrefs := searchHrefs(t, srv, "gecko"); len(hrefs) != 2 {
+		t.Fatalf("got %v, want two matches", hrefs)
+	}
+	for _, target := range []string{"/a", "/b", "/c", "/d"} {
+		if w := serve(srv, target); w.Code != http.StatusOK {
+			t.Errorf("GET %s: got status %d, want %d", target, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestSearchError(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "Hello",
+	}, func(srv *Server) {
+		srv.Searcher = failingSearcher{}
+	})
+
+	w := serve(srv, "/?s=hello")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("HTML search: got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	w = serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=hello")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("search API: got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"error"`) {
+		t.Fatalf("search API: got body %q, want a JSON error", body)
+	}
+}
+
+func TestContentFragments(t *testing.T) {
+	filler := strings.Repeat("Lorem ipsum dolor sit amet. ", 12)
+	files := map[string]string{
+		"long.md": "A zebra. " + filler + "Another zebra. " + filler + "A third zebra.",
+	}
+	for _, fragments := range []int{1, 3} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.ContentFragments = fragments
+		})
+		matches, err := srv.search(context.Background(), searchParams{input: "zebra", fields: storedFields})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 {
+			t.Fatalf("got %d matches, want 1", len(matches))
+		}
+		if got := strings.Count(string(matches[0].Content), "<mark>zebra</mark>"); got != fragments {
+			t.Errorf("ContentFragments %d: got %d highlighted terms in %q", fragments, got, matches[0].Content)
+		}
+	}
+}
+
+func TestSearchCode(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"fenced.md": "Call it like this:\n\n```go\nresult := myFunction(42)\n```",
+		"prose.md":  "The myFunction helper is described elsewhere.",
+	}, nil)
+	if hrefs := searchHrefs(t, srv, "code:myFunction"); !slices.Equal(hrefs, []string{"/fenced"}) {
+		t.Fatalf("got %v, want the page with the fenced block only", hrefs)
+	}
+	if hrefs := searchHrefs(t, srv, "myFunction"); len(hrefs) != 2 {
+		t.Fatalf("plain search: got %v, want both pages", hrefs)
+	}
+}
+
+// gatedSearcher is a BlugeSearcher whose Reload waits for a value on gate.
+type gatedSearcher struct {
+	*BlugeSearcher
+	gate chan struct{}
+}
+
+func (searcher gatedSearcher) Reload() error {
+	<-searcher.gate
+	return searcher.BlugeSearcher.Reload()
+}
+
+// waitForHrefs searches for input until the sorted hrefs equal want, or fails after a few seconds.
+func waitForHrefs(t *testing.T, srv *Server, input string, want []string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hrefs := searchHrefs(t, srv, input)
+		slices.Sort(hrefs)
+		if slices.Equal(hrefs, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %v, want %v", hrefs, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackgroundIndex(t *testing.T) {
+	searcher := gatedSearcher{NewBlugeSearcher(), make(chan struct{})}
+	srv := newTestServer(t, map[string]string{
+		"old.md": "The old ibex.",
+	}, func(srv *Server) {
+		srv.BackgroundIndex = true
+		srv.Searcher = searcher
+	})
+	searcher.gate <- struct{}{}
+	waitForHrefs(t, srv, "ibex", []string{"/old"})
+
+	if err := os.WriteFile(filepath.Join(srv.FsDir, "new.md"), []byte("The new ibex."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err != nil { // returns while the index rebuild is waiting
+		t.Fatal(err)
+	}
+	if _, ok := srv.Root().Files["new"]; !ok {
+		t.Fatal("navigation does not contain the new page")
+	}
+	if hrefs := searchHrefs(t, srv, "ibex"); !slices.Equal(hrefs, []string{"/old"}) {
+		t.Fatalf("got %v before the index has been rebuilt, want the old page only", hrefs)
+	}
+
+	searcher.gate <- struct{}{}
+	waitForHrefs(t, srv, "ibex", []string{"/new", "/old"})
+}
+
+func TestSearchAPIShape(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"guide/setup.md": "Install the walrus.",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+	})
+	handler := http.HandlerFunc(srv.HandleSearchAPI)
+
+	if code := serve(handler, "/search?s=walrus").Code; code == http.StatusOK {
+		t.Fatalf("unauthorized request: got status %d", code)
+	}
+
+	// the keys read by static/live-search.js
+	var matches []map[string]any
+	if err := json.Unmarshal(serve(handler, "/search?s=walrus&auth=secret").Body.Bytes(), &matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	for key, want := range map[string]string{
+		"href": "/guide/setup",
+		"path": "guide",
+		"name": "setup.md",
+	} {
+		if got, _ := matches[0][key].(string); got != want {
+			t.Errorf("got %s %q, want %q", key, got, want)
+		}
+	}
+	if content, _ := matches[0]["content"].(string); !strings.Contains(content, "walrus") {
+		t.Errorf("got content %q, want a snippet", content)
+	}
+
+	body := serve(srv, "/?auth=secret").Body.String()
+	if !strings.Contains(body, `data-api="/search"`) {
+		t.Errorf("search input does not reference the API: %s", body)
+	}
+}
+
+func TestSearchSnippetWithoutMarkdown(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"notes.md": "This is **important** and _emphasized_.",
+	}, nil)
+	matches, err := srv.search(context.Background(), searchParams{input: "important", fields: storedFields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	content := string(matches[0].Content)
+	if !strings.Contains(content, "<mark>important</mark>") || strings.ContainsAny(content, "*_") {
+		t.Fatalf("got snippet %q, want the highlighted word without markdown syntax", content)
+	}
+}
+
+func TestSearchIgnoresLinkTargets(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"notes.md": "See [the manual](https://example.com/zebrafish).",
+	}, nil)
+	if hrefs := searchHrefs(t, srv, "zebrafish"); len(hrefs) > 0 {
+		t.Errorf("link target is matched: %v", hrefs)
+	}
+	if hrefs := searchHrefs(t, srv, "manual"); !slices.Equal(hrefs, []string{"/notes"}) {
+		t.Errorf("link text: got %v, want /notes", hrefs)
+	}
+	if hrefs := searchHrefs(t, srv, "link:zebrafish"); !slices.Equal(hrefs, []string{"/notes"}) {
+		t.Errorf("link: prefix: got %v, want /notes", hrefs)
+	}
+}
+
+func TestSlowSearchLog(t *testing.T) {
+	var buf bytes.Buffer
+	srv := newTestServer(t, map[string]string{
+		"notes.md": "The lynx.",
+	}, func(srv *Server) {
+		srv.Logger = log.New(&buf, "", 0)
+		srv.SlowSearchThreshold = time.Nanosecond
+	})
+	searchHrefs(t, srv, "lynx\x1b[2J")
+	if got := buf.String(); !strings.HasPrefix(got, `slow search "lynx\x1b[2j" took `) {
+		t.Fatalf("got log %q, want a slow search entry with the escaped query", got)
+	}
+
+	buf.Reset()
+	srv.SlowSearchThreshold = 0
+	searchHrefs(t, srv, "lynx")
+	if buf.Len() > 0 {
+		t.Fatalf("got log %q without threshold", buf.String())
+	}
+}
+
+func TestHighlightStrategy(t *testing.T) {
+	files := map[string]string{
+		"birds.md": "An owl appeared at dawn." + strings.Repeat(" Nothing else happened here.", 20) + " Later, a parliament of owls: every owl in the parliament hooted.",
+	}
+	for _, test := range []struct {
+		strategy      HighlightStrategy
+		want, notWant string
+	}{
+		{HighlightBest, "<mark>parliament</mark>", "appeared"},
+		{HighlightFirst, "<mark>owl</mark> appeared", "parliament"},
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.HighlightStrategy = test.strategy
+		})
+		matches, err := srv.search(context.Background(), searchParams{input: "owl parliament", fields: storedFields})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 {
+			t.Fatalf("got %d matches, want 1", len(matches))
+		}
+		if content := string(matches[0].Content); !strings.Contains(content, test.want) || strings.Contains(content, test.notWant) {
+			t.Errorf("strategy %d: got %q, want %q without %q", test.strategy, content, test.want, test.notWant)
+		}
+	}
+}
+
+func TestMinSearchTermLength(t *testing.T) {
+	files := map[string]string{
+		"both.md": "The ox and the cat.",
+		"cat.md":  "The cat alone.",
+	}
+	for _, test := range []struct {
+		min   int
+		input string
+		want  []string
+	}{
+		{0, "ox cat", []string{"/both"}},
+		{3, "ox cat", []string{"/both", "/cat"}}, // "ox" is dropped
+		{3, "ox", []string{"/both"}},             // the only term is kept
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.MinSearchTermLength = test.min
+		})
+		hrefs := searchHrefs(t, srv, test.input)
+		slices.Sort(hrefs)
+		if !slices.Equal(hrefs, test.want) {
+			t.Errorf("min %d, %q: got %v, want %v", test.min, test.input, hrefs, test.want)
+		}
+	}
+}
+
+func TestResultURLTransform(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"guide/setup.md": "Feed the alpaca.",
+	}, func(srv *Server) {
+		srv.ResultURLTransform = func(href string) string {
+			return "/kb/article" + href
+		}
+	})
+	if hrefs := searchHrefs(t, srv, "alpaca"); !slices.Equal(hrefs, []string{"/kb/article/guide/setup"}) {
+		t.Errorf("got %v, want the transformed href", hrefs)
+	}
+	var matches []DocumentMatch
+	if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=alpaca").Body.Bytes(), &matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Href != "/kb/article/guide/setup" {
+		t.Errorf("search API: got %+v, want the transformed href", matches)
+	}
+}
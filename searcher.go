@@ -0,0 +1,61 @@
+package markdump
+
+import "context"
+
+// Searcher is a search backend.
+type Searcher interface {
+	// Index adds a document to the next index. It is called by Server.Reload.
+	Index(doc SearchDoc)
+	// Reload replaces the searchable index with the documents which have been added since the previous call.
+	Reload() error
+	// Search calls fn for each match, ordered by score. It stops if fn returns an error or ctx is done. If request.Facets is set, it returns the number of all matches per section. It is called concurrently, also while Index and Reload are running.
+	Search(ctx context.Context, request SearchRequest, fn func(DocumentMatch) error) (map[string]uint64, error)
+}
+
+// Suggester is implemented by search backends which can complete the titles of documents.
+type Suggester interface {
+	// Suggest calls fn for up to maxMatches documents whose title contains words starting with each of the given lowercase prefixes.
+	Suggest(ctx context.Context, prefixes []string, fn func(Suggestion) error) error
+}
+
+// Suggestion is a document whose title matches the input.
+type Suggestion struct {
+	Href  string `json:"href"`
+	Title string `json:"title"`
+}
+
+// SearchDoc is a file or dir in the search index.
+type SearchDoc struct {
+	ID      string // URL
+	Path    string // titles of the parent dirs, see Dir.PathString, without a trailing separator
+	Name    string // file or dir name
+	Title   string // display title, for suggestions
+	Content string // plain text, empty for dirs without readme
+	Code    string // content of code blocks, also contained in Content
+	Links   string // link targets, separated by spaces, not contained in Content
+	Section string // slug of the top-level dir, empty if the document is in the root dir
+}
+
+// SearchRequest is a normalized search query.
+type SearchRequest struct {
+	Code      []string // lowercase terms which must occur in code blocks, from "code:" prefixed words
+	Facets    bool     // count matches per section
+	Fields    []string // stored fields to include in the matches, see storedFields
+	Fragments int      // maximum number of highlighted fragments of the content field, at least one
+	Highlight HighlightStrategy
+	Links     []string // lowercase terms which must occur in link targets, from "link:" prefixed words
+	Loose     bool     // match documents which contain any term instead of all terms
+	Scope     string   // URL of the dir to search in, empty means everywhere
+	Terms     []string // lowercase, unique, in input order
+}
+
+// HighlightStrategy determines which fragments of the content field are highlighted in search results.
+type HighlightStrategy int
+
+const (
+	HighlightBest  HighlightStrategy = iota // fragments which contain the most distinct terms
+	HighlightFirst                          // fragments around the earliest term occurrences, SubstringSearcher always does this
+)
+
+// maxMatches is the maximum number of matches returned by a Searcher.
+const maxMatches = 10
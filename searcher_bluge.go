@@ -0,0 +1,315 @@
+package markdump
+
+import (
+	"context"
+	"html/template"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/blugelabs/bluge"
+	"github.com/blugelabs/bluge/analysis"
+	"github.com/blugelabs/bluge/analysis/token"
+	"github.com/blugelabs/bluge/analysis/tokenizer"
+	"github.com/blugelabs/bluge/index"
+	"github.com/blugelabs/bluge/search"
+	"github.com/blugelabs/bluge/search/aggregations"
+	"github.com/blugelabs/bluge/search/highlight"
+)
+
+// BlugeSearcher is a full-text search with an in-memory bluge index.
+type BlugeSearcher struct {
+	SuggestMinGram int // minimum length of indexed title prefixes for suggestions, default: 2
+	SuggestMaxGram int // maximum length of indexed title prefixes for suggestions, longer input is matched by its prefix and filtered, default: 12
+
+	batch           *index.Batch
+	reader          atomic.Pointer[bluge.Reader]
+	suggestAnalyzer *analysis.Analyzer // built by Index
+}
+
+func NewBlugeSearcher() *BlugeSearcher {
+	return &BlugeSearcher{
+		batch: bluge.NewBatch(),
+	}
+}
+
+func (searcher *BlugeSearcher) Index(doc SearchDoc) {
+	bdoc := bluge.NewDocument(doc.ID) // _id
+	bdoc.AddField(bluge.NewTextField("path", doc.Path).StoreValue())
+	bdoc.AddField(bluge.NewTextField("name", doc.Name).SearchTermPositions().StoreValue())
+	if doc.Content != "" {
+		bdoc.AddField(bluge.NewTextField("content", doc.Content).SearchTermPositions().StoreValue())
+	}
+	if doc.Code != "" {
+		bdoc.AddField(bluge.NewTextField("code", doc.Code))
+	}
+	if doc.Links != "" {
+		bdoc.AddField(bluge.NewTextField("links", doc.Links))
+	}
+	if doc.Section != "" {
+		bdoc.AddField(bluge.NewKeywordField("section", doc.Section).Aggregatable())
+	}
+	if doc.Title != "" {
+		bdoc.AddField(bluge.NewStoredOnlyField("title", []byte(doc.Title)))
+		bdoc.AddField(bluge.NewTextField("suggest", doc.Title).WithAnalyzer(searcher.analyzer()))
+	}
+	bdoc.AddField(bluge.NewCompositeFieldIncluding("_all", []string{"name", "content"}))
+	searcher.batch.Update(bdoc.ID(), bdoc)
+}
+
+// analyzer returns the edge n-gram analyzer of the suggest field.
+func (searcher *BlugeSearcher) analyzer() *analysis.Analyzer {
+	if searcher.suggestAnalyzer == nil {
+		searcher.suggestAnalyzer = &analysis.Analyzer{
+			Tokenizer: tokenizer.NewUnicodeTokenizer(),
+			TokenFilters: []analysis.TokenFilter{
+				token.NewLowerCaseFilter(),
+				token.NewEdgeNgramFilter(token.FRONT, searcher.minGram(), searcher.maxGram()),
+			},
+		}
+	}
+	return searcher.suggestAnalyzer
+}
+
+func (searcher *BlugeSearcher) minGram() int {
+	if searcher.SuggestMinGram > 0 {
+		return searcher.SuggestMinGram
+	}
+	return 2
+}
+
+func (searcher *BlugeSearcher) maxGram() int {
+	if searcher.SuggestMaxGram > 0 {
+		return max(searcher.SuggestMaxGram, searcher.minGram())
+	}
+	return max(12, searcher.minGram())
+}
+
+func (searcher *BlugeSearcher) Reload() error {
+	indexWriter, err := bluge.OpenWriter(bluge.InMemoryOnlyConfig())
+	if err != nil {
+		return err
+	}
+	if err := indexWriter.Batch(searcher.batch); err != nil {
+		return err
+	}
+	reader, err := indexWriter.Reader() // reader is a snapshot
+	if err != nil {
+		return err
+	}
+	searcher.batch = bluge.NewBatch()
+	if old := searcher.reader.Swap(reader); old != nil {
+		time.AfterFunc(gracePeriod, func() {
+			old.Close() // searches which are still running on the old reader might fail
+		})
+	}
+	return nil
+}
+
+func (searcher *BlugeSearcher) Search(ctx context.Context, request SearchRequest, fn func(DocumentMatch) error) (map[string]uint64, error) {
+	query := bluge.NewBooleanQuery()
+	if len(request.Terms) > 0 {
+		query.AddMust(termsQuery("_all", request.Terms, !request.Loose))
+	}
+	if len(request.Code) > 0 {
+		query.AddMust(termsQuery("code", request.Code, true))
+	}
+	if len(request.Links) > 0 {
+		query.AddMust(termsQuery("links", request.Links, true))
+	}
+	if request.Scope != "" {
+		query.AddMust(scopeQuery(request.Scope))
+	}
+
+	searchRequest := bluge.NewTopNSearch(maxMatches, query).IncludeLocations()
+	if request.Facets {
+		searchRequest.AddAggregation("sections", aggregations.NewTermsAggregation(search.Field("section"), 100))
+	}
+
+	highlighter := highlight.NewHTMLHighlighter()
+
+	reader := searcher.reader.Load()
+	if reader == nil {
+		return nil, nil // not loaded yet
+	}
+	dmi, err := reader.Search(ctx, searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	next, err := dmi.Next()
+	for ; err == nil && next != nil; next, err = dmi.Next() {
+		var match DocumentMatch
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			if field != "_id" && !slices.Contains(request.Fields, field) {
+				return true
+			}
+			switch field {
+			case "_id":
+				match.Href = template.URL(value)
+			case "path":
+				match.Path = string(value)
+			case "name":
+				match.Name = template.HTML(value)
+				if locations, ok := next.Locations[field]; ok {
+					if fragment := highlighter.BestFragment(locations, value); len(fragment) > 0 {
+						match.Name = template.HTML(fragment)
+					}
+				}
+			case "content":
+				if locations, ok := next.Locations[field]; ok {
+					// fragments are HTML-escaped by the highlighter
+					var fragments []string
+					if request.Highlight == HighlightFirst {
+						fragments = firstFragments(locations, value, max(1, request.Fragments))
+					} else {
+						fragments = highlighter.BestFragments(locations, value, max(1, request.Fragments))
+					}
+					match.Content = template.HTML(strings.Join(fragments, " "))
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(match); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var facets map[string]uint64
+	if request.Facets {
+		facets = make(map[string]uint64)
+		for _, bucket := range dmi.Aggregations().Buckets("sections") {
+			facets[bucket.Name()] = bucket.Count()
+		}
+	}
+	return facets, nil
+}
+
+// Suggest looks up each prefix, cropped to the maximum n-gram length, in the suggest field. Shorter prefixes than the minimum n-gram length match nothing.
+func (searcher *BlugeSearcher) Suggest(ctx context.Context, prefixes []string, fn func(Suggestion) error) error {
+	reader := searcher.reader.Load()
+	if reader == nil {
+		return nil // not loaded yet
+	}
+	query := bluge.NewBooleanQuery()
+	for _, prefix := range prefixes {
+		if runes := []rune(prefix); len(runes) > searcher.maxGram() {
+			prefix = string(runes[:searcher.maxGram()])
+		}
+		query.AddMust(bluge.NewTermQuery(prefix).SetField("suggest"))
+	}
+	dmi, err := reader.Search(ctx, bluge.NewTopNSearch(10*maxMatches, query)) // more, as some are filtered
+	if err != nil {
+		return err
+	}
+	var count int
+	next, err := dmi.Next()
+	for ; err == nil && next != nil && count < maxMatches; next, err = dmi.Next() {
+		var suggestion Suggestion
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			switch field {
+			case "_id":
+				suggestion.Href = string(value)
+			case "title":
+				suggestion.Title = string(value)
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if !titleMatches(suggestion.Title, prefixes) {
+			continue // prefix was cropped
+		}
+		if err := fn(suggestion); err != nil {
+			return err
+		}
+		count++
+	}
+	return err
+}
+
+// scopeQuery matches the dir with the given URL and everything below it.
+func scopeQuery(url string) bluge.Query {
+	return bluge.NewBooleanQuery().
+		AddShould(bluge.NewTermQuery(url).SetField("_id")).
+		AddShould(bluge.NewPrefixQuery(url + "/").SetField("_id")).
+		SetMinShould(1)
+}
+
+// termsQuery returns a query which matches documents whose field contains all terms (if strict) or any term (if not strict).
+func termsQuery(field string, terms []string, strict bool) bluge.Query {
+	query := bluge.NewBooleanQuery()
+	for _, term := range terms {
+		termQuery := bluge.NewBooleanQuery()
+		termQuery.AddShould(bluge.NewFuzzyQuery(term).SetField(field).SetFuzziness(1))
+		termQuery.AddShould(bluge.NewPrefixQuery(term).SetField(field))
+		termQuery.AddShould(bluge.NewWildcardQuery("*" + term + "*").SetField(field))
+		if strict {
+			query.AddMust(termQuery)
+		} else {
+			query.AddShould(termQuery)
+		}
+	}
+	if !strict {
+		query.SetMinShould(1)
+	}
+	if len(terms) > 1 {
+		// rank documents with adjacent words higher
+		for _, phrase := range phraseQueries(field, terms) {
+			query.AddShould(phrase.SetBoost(2))
+		}
+	}
+	return query
+}
+
+// phraseQueries returns queries which match the terms as a phrase. They use the positions of the individual fields, as adjacency across fields would be meaningless.
+func phraseQueries(field string, terms []string) []*bluge.MatchPhraseQuery {
+	fields := []string{field}
+	if field == "_all" {
+		fields = []string{"name", "content"}
+	}
+	var queries []*bluge.MatchPhraseQuery
+	for _, f := range fields {
+		queries = append(queries, bluge.NewMatchPhraseQuery(strings.Join(terms, " ")).SetField(f).SetSlop(1))
+	}
+	return queries
+}
+
+// firstFragments is like highlighter.BestFragments, but returns up to num non-overlapping fragments in the order of the term locations, so the earliest occurrence comes first.
+func firstFragments(tlm search.TermLocationMap, orig []byte, num int) []string {
+	locations := highlight.OrderTermLocations(tlm)
+	var selected []*highlight.Fragment
+OUTER:
+	for _, fragment := range highlight.NewSimpleFragmenter().Fragment(orig, locations) {
+		if len(selected) >= num {
+			break
+		}
+		for _, other := range selected {
+			if fragment.Overlaps(other) {
+				continue OUTER
+			}
+		}
+		selected = append(selected, fragment)
+	}
+
+	locations.MergeOverlapping()
+	formatter := highlight.NewHTMLFragmentFormatter()
+	var fragments = make([]string, len(selected))
+	for i, fragment := range selected {
+		if fragment.Start != 0 {
+			fragments[i] += highlight.DefaultSeparator
+		}
+		fragments[i] += formatter.Format(fragment, locations)
+		if fragment.End != len(fragment.Orig) {
+			fragments[i] += highlight.DefaultSeparator
+		}
+	}
+	return fragments
+}
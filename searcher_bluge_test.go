@@ -0,0 +1,55 @@
+package markdump
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/blugelabs/bluge"
+)
+
+func TestBlugeAdjacentWordsRankHigher(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"scattered.md": "A release is planned. We took notes. More release info.", // would rank higher without the phrase query
+		"adjacent.md":  "The release notes are in the archive.",
+	}, nil)
+	hrefs := searchHrefs(t, srv, "release notes")
+	if len(hrefs) != 2 || hrefs[0] != "/adjacent" {
+		t.Fatalf("got %v, want /adjacent first", hrefs)
+	}
+}
+
+func TestBlugePhraseQueries(t *testing.T) {
+	searcher := NewBlugeSearcher()
+	searcher.Index(SearchDoc{ID: "/adjacent", Name: "adjacent.md", Content: "Read the release notes first."})
+	searcher.Index(SearchDoc{ID: "/scattered", Name: "scattered.md", Content: "The release is near. Take notes."})
+	searcher.Index(SearchDoc{ID: "/across", Name: "release.md", Content: "Notes about the next version."})
+	if err := searcher.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	query := bluge.NewBooleanQuery()
+	for _, phrase := range phraseQueries("_all", []string{"release", "notes"}) {
+		query.AddShould(phrase)
+	}
+	dmi, err := searcher.reader.Load().Search(context.Background(), bluge.NewTopNSearch(10, query))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	next, err := dmi.Next()
+	for ; err == nil && next != nil; next, err = dmi.Next() {
+		err = next.VisitStoredFields(func(field string, value []byte) bool {
+			if field == "_id" {
+				ids = append(ids, string(value))
+			}
+			return true
+		})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []string{"/adjacent"}) {
+		t.Fatalf("got %v, want the document with adjacent words only", ids)
+	}
+}
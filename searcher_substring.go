@@ -0,0 +1,211 @@
+package markdump
+
+import (
+	"context"
+	"html"
+	"html/template"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// SubstringSearcher is a simple search for small sites. A document matches if its name or content contains the terms as substrings.
+type SubstringSearcher struct {
+	docs atomic.Pointer[[]SearchDoc] // replaced by Reload
+	next []SearchDoc
+}
+
+func NewSubstringSearcher() *SubstringSearcher {
+	return &SubstringSearcher{}
+}
+
+func (searcher *SubstringSearcher) Index(doc SearchDoc) {
+	searcher.next = append(searcher.next, doc)
+}
+
+func (searcher *SubstringSearcher) Reload() error {
+	docs := searcher.next
+	searcher.docs.Store(&docs)
+	searcher.next = nil
+	return nil
+}
+
+func (searcher *SubstringSearcher) Search(ctx context.Context, request SearchRequest, fn func(DocumentMatch) error) (map[string]uint64, error) {
+	type scored struct {
+		doc   *SearchDoc
+		score int
+	}
+	var matches []scored
+	var facets map[string]uint64
+	if request.Facets {
+		facets = make(map[string]uint64)
+	}
+	var docs []SearchDoc
+	if p := searcher.docs.Load(); p != nil {
+		docs = *p
+	}
+	for i := range docs {
+		if i%100 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		doc := &docs[i]
+		if request.Scope != "" && doc.ID != request.Scope && !strings.HasPrefix(doc.ID, request.Scope+"/") {
+			continue
+		}
+		name := strings.ToLower(doc.Name)
+		content := strings.ToLower(doc.Content)
+		var score, found int
+		for _, term := range request.Terms {
+			n := 2*strings.Count(name, term) + strings.Count(content, term) // matches in name count double
+			if n > 0 {
+				found++
+			}
+			score += n
+		}
+		if len(request.Terms) > 0 && (found == 0 || (!request.Loose && found < len(request.Terms))) {
+			continue
+		}
+		if !containsAll(strings.ToLower(doc.Code), request.Code) || !containsAll(strings.ToLower(doc.Links), request.Links) {
+			continue
+		}
+		matches = append(matches, scored{doc, score})
+		if request.Facets && doc.Section != "" {
+			facets[doc.Section]++
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	for _, m := range matches[:min(maxMatches, len(matches))] {
+		var match = DocumentMatch{
+			Href: template.URL(m.doc.ID),
+		}
+		if slices.Contains(request.Fields, "path") {
+			match.Path = m.doc.Path
+		}
+		if slices.Contains(request.Fields, "name") {
+			match.Name = highlightSubstrings(m.doc.Name, request.Terms, 0, 1)
+			if match.Name == "" {
+				match.Name = template.HTML(html.EscapeString(m.doc.Name))
+			}
+		}
+		if slices.Contains(request.Fields, "content") {
+			match.Content = highlightSubstrings(m.doc.Content, append(slices.Clip(request.Terms), request.Code...), 200, request.Fragments)
+		}
+		if err := fn(match); err != nil {
+			return nil, err
+		}
+	}
+	return facets, nil
+}
+
+// Suggest returns the documents whose title contains words starting with each of the prefixes, in index order.
+func (searcher *SubstringSearcher) Suggest(ctx context.Context, prefixes []string, fn func(Suggestion) error) error {
+	var docs []SearchDoc
+	if p := searcher.docs.Load(); p != nil {
+		docs = *p
+	}
+	var count int
+	for i := range docs {
+		if count >= maxMatches {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if docs[i].Title == "" || !titleMatches(docs[i].Title, prefixes) {
+			continue
+		}
+		if err := fn(Suggestion{Href: docs[i].ID, Title: docs[i].Title}); err != nil {
+			return err
+		}
+		count++
+	}
+	return nil
+}
+
+// highlightSubstrings returns up to n HTML fragments of s with about the given length around term occurrences, or all of s if length is zero. Term occurrences are wrapped in <mark> elements. It returns an empty string if s contains no term.
+func highlightSubstrings(s string, terms []string, length, n int) template.HTML {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		lower = s // lowercasing changed byte offsets, so match only case-sensitive
+	}
+
+	var fragments []string
+	for from := 0; len(fragments) < max(1, n); {
+		first := -1
+		for _, term := range terms {
+			if i := strings.Index(lower[from:], term); i >= 0 && (first < 0 || from+i < first) {
+				first = from + i
+			}
+		}
+		if first < 0 {
+			break
+		}
+
+		start, end := 0, len(s)
+		if length > 0 {
+			start = max(from, first-length/4)
+			end = min(len(s), start+length)
+		}
+		for start > from && !isRuneStart(s[start]) {
+			start--
+		}
+		for end < len(s) && !isRuneStart(s[end]) {
+			end++
+		}
+		fragments = append(fragments, highlightFragment(s, lower, terms, start, end))
+		from = end
+	}
+	return template.HTML(strings.Join(fragments, " "))
+}
+
+// highlightFragment returns s[start:end] as HTML with marked terms and ellipses.
+func highlightFragment(s, lower string, terms []string, start, end int) string {
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		var matched string
+		for _, term := range terms {
+			if strings.HasPrefix(lower[i:], term) && len(term) > len(matched) {
+				matched = term
+			}
+		}
+		if matched != "" && i+len(matched) <= end {
+			sb.WriteString("<mark>")
+			sb.WriteString(html.EscapeString(s[i : i+len(matched)]))
+			sb.WriteString("</mark>")
+			i += len(matched)
+			continue
+		}
+		j := i + 1
+		for j < end && !isRuneStart(s[j]) {
+			j++
+		}
+		sb.WriteString(html.EscapeString(s[i:j]))
+		i = j
+	}
+	if end < len(s) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
+
+func containsAll(s string, terms []string) bool {
+	for _, term := range terms {
+		if !strings.Contains(s, term) {
+			return false
+		}
+	}
+	return true
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,76 @@
+package markdump
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+var (
+	_ Searcher  = (*BlugeSearcher)(nil)
+	_ Searcher  = (*SubstringSearcher)(nil)
+	_ Suggester = (*BlugeSearcher)(nil)
+)
+
+func TestSearchers(t *testing.T) {
+	for name, searcher := range map[string]Searcher{
+		"bluge":     NewBlugeSearcher(),
+		"substring": NewSubstringSearcher(),
+	} {
+		searcher.Index(SearchDoc{ID: "/animals/otter", Path: "animals", Name: "otter.md", Title: "otter", Content: "The otter swims in the river."})
+		searcher.Index(SearchDoc{ID: "/animals/eagle", Path: "animals", Name: "eagle.md", Title: "eagle", Content: "The eagle flies over the river."})
+		searcher.Index(SearchDoc{ID: "/plants/oak", Path: "plants", Name: "oak.md", Title: "oak", Content: "The oak grows slowly."})
+		if err := searcher.Reload(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		for _, test := range []struct {
+			terms []string
+			want  []string
+		}{
+			{[]string{"otter"}, []string{"/animals/otter"}},
+			{[]string{"river"}, []string{"/animals/eagle", "/animals/otter"}},
+			{[]string{"river", "flies"}, []string{"/animals/eagle"}},
+			{[]string{"cactus"}, nil},
+		} {
+			var hrefs []string
+			_, err := searcher.Search(context.Background(), SearchRequest{Terms: test.terms, Fields: storedFields}, func(match DocumentMatch) error {
+				hrefs = append(hrefs, string(match.Href))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			slices.Sort(hrefs)
+			if !slices.Equal(hrefs, test.want) {
+				t.Errorf("%s, terms %v: got %v, want %v", name, test.terms, hrefs, test.want)
+			}
+		}
+	}
+}
+
+func TestSearchCanceled(t *testing.T) {
+	for name, searcher := range map[string]Searcher{
+		"bluge":     NewBlugeSearcher(),
+		"substring": NewSubstringSearcher(),
+	} {
+		for i := 0; i < 100; i++ {
+			searcher.Index(SearchDoc{ID: fmt.Sprintf("/doc%d", i), Name: fmt.Sprintf("doc%d.md", i), Content: "The same words in every document."})
+		}
+		if err := searcher.Reload(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var count int
+		_, err := searcher.Search(ctx, SearchRequest{Terms: []string{"words"}, Fields: storedFields}, func(match DocumentMatch) error {
+			count++
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: got error %v after %d matches, want %v", name, err, count, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package markdump
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// searchIgnoreFile contains gitignore-style patterns of files and dirs which are not indexed, but still served.
+const searchIgnoreFile = ".searchignore"
+
+type ignorePattern struct {
+	pattern  string
+	anchored bool // matches the path relative to the content folder, else the name at any level
+	dirOnly  bool
+	negate   bool
+}
+
+// ignoreList is a list of gitignore-style patterns. It supports negation with "!", anchoring with a leading or inner "/", dirs with a trailing "/" and a leading "**/". Other "**" are not supported.
+type ignoreList []ignorePattern
+
+// readIgnoreList reads the patterns in the given file. A missing file results in an empty list.
+func readIgnoreList(name string) (ignoreList, error) {
+	content, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list ignoreList
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var p ignorePattern
+		line, p.negate = strings.CutPrefix(line, "!")
+		line, p.dirOnly = strings.CutSuffix(line, "/")
+		line = strings.TrimPrefix(line, "**/")
+		p.anchored = strings.Contains(line, "/")
+		p.pattern = strings.TrimPrefix(line, "/")
+		if p.pattern != "" {
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+// ignored reports whether rel, a slash-separated path relative to the content folder, or one of its parent dirs is matched by the list.
+func (list ignoreList) ignored(rel string, isDir bool) bool {
+	if len(list) == 0 {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	for i := range segments {
+		prefix := strings.Join(segments[:i+1], "/")
+		if list.matches(prefix, isDir || i < len(segments)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches applies the patterns to p. The last matching pattern wins.
+func (list ignoreList) matches(p string, isDir bool) bool {
+	var ignored bool
+	for _, pattern := range list {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		target := p
+		if !pattern.anchored {
+			target = path.Base(p)
+		}
+		if ok, _ := path.Match(pattern.pattern, target); ok {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
+
+// searchIgnored reports whether the file or dir at fsPath must not be indexed, according to the .searchignore file in the content folder.
+func (l *loader) searchIgnored(fsPath string, isDir bool) bool {
+	rel, err := filepath.Rel(l.fsDir, fsPath)
+	if err != nil {
+		return false
+	}
+	return l.searchIgnore.ignored(filepath.ToSlash(rel), isDir)
+}
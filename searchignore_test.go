@@ -0,0 +1,52 @@
+package markdump
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestSearchIgnore(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		".searchignore":       "# generated\narchive/\nscratch-*.md\n",
+		"archive/2019/old.md": "The okapi in the archive.",
+		"archive/keep.md":     "Another okapi.",
+		"notes.md":            "A current okapi.",
+		"scratch-notes.md":    "A draft okapi.",
+		"sub/archive.md":      "A file named like the dir okapi.", // dir patterns do not match files
+	}, nil)
+	if hrefs := searchHrefs(t, srv, "okapi"); !slices.Equal(hrefs, []string{"/notes", "/sub/archive"}) {
+		t.Errorf("got %v, want the pages which are not ignored", hrefs)
+	}
+	for _, target := range []string{"/archive", "/archive/2019/old", "/scratch-notes"} {
+		if code := serve(srv, target).Code; code != http.StatusOK {
+			t.Errorf("GET %s: got status %d, want %d", target, code, http.StatusOK)
+		}
+	}
+}
+
+func TestIgnoreList(t *testing.T) {
+	list := ignoreList{
+		{pattern: "build", dirOnly: true},
+		{pattern: "docs/api", anchored: true},
+		{pattern: "*.log"},
+		{pattern: "keep.log", negate: true},
+	}
+	for _, test := range []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"build", true, true},
+		{"build", false, false},
+		{"a/build/x.md", false, true},
+		{"docs/api/ref.md", false, true},
+		{"other/docs/api/ref.md", false, false},
+		{"a/b.log", false, true},
+		{"a/keep.log", false, false},
+	} {
+		if got := list.ignored(test.rel, test.isDir); got != test.want {
+			t.Errorf("ignored(%q, %t): got %t, want %t", test.rel, test.isDir, got, test.want)
+		}
+	}
+}
@@ -1,23 +1,30 @@
 package markdump
 
 import (
-	"context"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"hash"
 	"html/template"
+	"io/fs"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
-	"github.com/blugelabs/bluge"
-	"github.com/blugelabs/bluge/index"
-	"github.com/blugelabs/bluge/search/highlight"
 	"gitlab.com/golang-commonmark/markdown"
 	"golang.org/x/text/runes"
 	"golang.org/x/text/transform"
@@ -27,11 +34,119 @@ import (
 var md = markdown.New(markdown.HTML(true), markdown.Linkify(true), markdown.Typographer(true))
 
 type Server struct {
-	AuthTokens []string
-	FsDir      string
-	Root       *Dir
-	Reader     *bluge.Reader
-	RootTitle  string
+	Admonitions          bool   // render blockquotes starting with a GitHub-style alert like "> [!NOTE]" as styled admonitions
+	AuthTokenFile        string // file with additional authentication tokens, one per line, which is read again by Reload
+	AuthTokens           []string
+	BackgroundIndex      bool          // rebuild the search index in the background after Reload has updated the navigation, the old index is searched meanwhile
+	BaseURL              string        // absolute URL of the site, e.g. "https://wiki.example.com/", links to other hosts are external
+	CacheDir             string        // folder for caching rendered HTML across restarts, empty means no cache
+	CollapseSingleChild  bool          // list a chain of dirs, each containing nothing but the next one, as a single entry
+	ContentFragments     int           // maximum number of highlighted content fragments per search result, default: 1
+	DateDirs             bool          // recognize date-structured dirs like 2024/01/15, order them chronologically and set the Date of the files in them
+	DefinitionLists      bool          // render paragraphs like "Term\n: Definition" as definition lists
+	Details              bool          // render fenced blocks like ```details Title as collapsible details sections
+	DevMode              bool          // inject a script into pages which reloads them after a successful Reload, see HandleLiveReload
+	DirAssets            bool          // link a style.css and a script.js file of a dir into the pages in that dir and its subdirs
+	ExcerptSource        ExcerptSource // how the excerpts of files are derived, default: description from front matter, or first paragraph
+	ExternalLinkRel      bool          // add rel="nofollow ugc noopener" and target="_blank" to external links in rendered files
+	FailFast             bool          // abort Reload if a file or dir can't be read, instead of skipping it
+	Figures              bool          // wrap images which are alone in a paragraph in a figure, captioned with their alt text
+	FoldAttachmentCase   bool          // redirect requests for missing files to the only file in the same dir whose name differs in letter case only, like Image.PNG to image.png
+	FsDir                string
+	Funcs                template.FuncMap    // custom template functions, in addition to formatDate, reltime, slugify, static, t and truncate
+	GitRef               string              // load the files of this git ref, e.g. a branch, from the git repository in FsDir instead of the working tree
+	HideHome             bool                // omit the root link from the breadcrumbs
+	HighlightStrategy    HighlightStrategy   // how the highlighted content fragments of search results are selected, default: HighlightBest
+	HomeLabel            string              // label of the root link in the breadcrumbs, default: RootTitle
+	HomeURL              string              // target of the root link in the breadcrumbs, default: the root dir
+	HumanizeTitles       bool                // display "getting_started" as "Getting Started"
+	IncludeDrafts        bool                // load files with "draft: true" in their front matter, e.g. on a staging instance
+	KeepDuplicateResults bool                // return a readme file and its dir as separate search results
+	KeepEmptyDirs        bool                // keep dirs without pages, subdirs or listed attachments in the navigation, they are skipped by default
+	LetterIndexThreshold int                 // group dir listings with more entries by their first letter and display an A–Z bar, zero means never
+	ListAttachments      bool                // list files which are not displayed as pages, like PDFs or images, with their size and type
+	ListingLimit         int                 // display only so many entries in dir listings which are not paginated, with a link to display all, zero means no limit
+	LiveSearchDelay      time.Duration       // time after the last keystroke until the live search queries the search API, default: 200ms, negative means no live search
+	Logger               *log.Logger         // receives the slow search log lines, default: log.Default()
+	LooseFallback        bool                // if no document matches all search words, search for documents matching any of them
+	MIMETypes            map[string]string   // additional content types of attachments by file extension, e.g. ".mjs": "text/javascript", they apply to the whole process
+	MaxIndexedDocs       int                 // limit of documents in the search index, further files and dirs can be browsed but not searched, zero means no limit
+	MaxRenderedSize      int                 // truncate the HTML content of files which exceeds this number of bytes, zero means no limit
+	MaxURLLength         int                 // reply 414 to longer request URIs, zero means no limit
+	MinSearchTermLength  int                 // ignore shorter search words, unless all words are shorter, zero or one means no minimum
+	NegotiateImages      bool                // serve AVIF or WebP siblings of images to clients which accept them
+	NoIndex              bool                // ask search engines not to index any page
+	OmitReadmeResults    bool                // don't index readme files of subdirs separately, as their content is indexed with the dir anyway
+	PageSize             int                 // default number of entries per page in dir listings, can be overridden by the "per" query parameter, zero means no pagination
+	PathSeparator        string              // separates the dir titles in search results, default: " / "
+	Prefix               string              // URL path prefix, e.g. "/internal/", default: "/"
+	References           bool                // append a list of link reference definitions to rendered files
+	RenderCSV            bool                // display CSV files as tables, the raw files are still served with their extension
+	ResultURLTransform   func(string) string // maps the URLs of search results, e.g. if the pages are embedded under different paths, default: identity
+	RobotsPolicy         string              // "allow-all", "disallow-all" or a custom robots.txt, default: "allow-all" if public, else "disallow-all"
+	RootLandingFile      string              // markdown file name, displayed on the root dir page instead of the readme
+	RootTitle            string
+	SearchFields         []string      // default stored fields included in search API results, can be overridden by the "fields" query parameter
+	SearchTimeout        time.Duration // abort searches which take longer, zero means no timeout
+	Searcher             Searcher      // search backend, default: NewBlugeSearcher()
+	ServeHTML            bool          // treat HTML files as pages, displayed within the layout
+	SharedDir            string        // folder outside of the content folder with snippets which can be included like {{include: shared:legal/privacy.md}}
+	ShowRecent           int           // number of most recently modified files displayed on the root dir page
+	SidebarDepth         int           // levels of the navigation tree displayed in a sidebar, zero means no sidebar
+	SlowSearchThreshold  time.Duration // log searches which take longer, zero means no logging
+	StripComments        bool          // remove HTML comments from rendered files
+	Templates            fs.FS         // optional templates which override the embedded ones with the same file name
+	TitleFromH1          bool          // use the first level-one heading of a markdown file as its title, unless the front matter has one
+	TitleH1Strip         bool          // with TitleFromH1, remove that heading from the content, e.g. if custom templates display the title
+	Transliterate        bool          // use SlugifyTransliterated instead of Slugify
+
+	current      atomic.Pointer[state]      // replaced by Reload
+	indexGen     atomic.Uint64              // incremented by Reload, see rebuildIndex
+	indexMu      sync.Mutex                 // serializes index rebuilds, which might run in the background
+	reloadMu     sync.Mutex                 // serializes calls to Reload
+	reloadSubs   map[chan struct{}]struct{} // live reload connections, see HandleLiveReload
+	reloadSubsMu sync.Mutex
+}
+
+// gracePeriod is the time after which the resources of a replaced state, like the git snapshot or the search index reader, are released.
+const gracePeriod = time.Minute
+
+// state is the content served by a Server. Reload builds a new state and replaces the old one as a whole, so requests are served without locking.
+type state struct {
+	root       *Dir
+	aliases    map[string]string   // alias path to URL
+	authTokens []string            // srv.AuthTokens and the tokens from srv.AuthTokenFile
+	drafts     map[string]struct{} // file system paths, not served as attachments
+	footer     template.HTML       // displayed on every page
+	notFound   template.HTML       // content of 404 error pages
+	pages      []*File             // without readmes, most recently modified first
+	recent     []*File             // most recently modified files
+	snapshot   string              // temporary folder with the files of GitRef
+	tmpl       *templates
+	version    string // changes if any file has been added, removed or modified
+}
+
+// Root returns the root dir, or nil if the server has not been loaded yet.
+func (srv *Server) Root() *Dir {
+	if st := srv.current.Load(); st != nil {
+		return st.root
+	}
+	return nil
+}
+
+// entries returns the entries of dir for display.
+func (srv *Server) entries(dir *Dir) []Entry {
+	if !srv.CollapseSingleChild {
+		return dir.EntryList
+	}
+	var entries = make([]Entry, len(dir.EntryList))
+	for i, entry := range dir.EntryList {
+		if subdir, ok := entry.(*Dir); ok {
+			entry = collapse(subdir)
+		}
+		entries[i] = entry
+	}
+	return entries
 }
 
 type Entry interface {
@@ -41,108 +156,359 @@ type Entry interface {
 }
 
 type Dir struct {
-	FsPath    string // required for serving files by slug
-	Path      []*Dir // including root
-	title     string
-	url       string
-	Subdirs   map[string]*Dir
-	Files     map[string]*File
-	EntryList []Entry
+	FsPath      string // required for serving files by slug
+	Path        []*Dir // including root
+	title       string
+	url         string
+	landing     *File // overrides readme
+	date        dirDate
+	Subdirs     map[string]*Dir
+	Files       map[string]*File
+	Attachments map[string]*Attachment // by file name, empty unless Server.ListAttachments is set
+	EntryList   []Entry
+	styles      []string // URLs of the style.css files of dir and its parents, see Server.DirAssets
+	scripts     []string // URLs of the script.js files of dir and its parents, see Server.DirAssets
 }
 
 func (dir *Dir) IsDir() bool {
 	return true
 }
 
-// Load loads subdirs and files of dir.
-func (dir *Dir) Load(batch *index.Batch) error {
+// collapsedDir is a chain of dirs, where each dir except the last one contains nothing but the next one.
+type collapsedDir []*Dir
+
+// collapse returns dir, or a collapsedDir if dir contains nothing but a single subdir.
+func collapse(dir *Dir) Entry {
+	var chain = collapsedDir{dir}
+	for len(dir.EntryList) == 1 {
+		subdir, ok := dir.EntryList[0].(*Dir)
+		if !ok {
+			break
+		}
+		chain = append(chain, subdir)
+		dir = subdir
+	}
+	if len(chain) == 1 {
+		return dir
+	}
+	return chain
+}
+
+func (chain collapsedDir) IsDir() bool {
+	return true
+}
+
+func (chain collapsedDir) Title() string {
+	var titles = make([]string, len(chain))
+	for i, dir := range chain {
+		titles[i] = dir.title
+	}
+	return strings.Join(titles, " / ")
+}
+
+func (chain collapsedDir) URL() string {
+	return chain[len(chain)-1].url
+}
+
+// loader holds the state of a reload.
+type loader struct {
+	srv          *Server
+	fsDir        string              // content folder, srv.FsDir or a snapshot of srv.GitRef
+	aliases      map[string]string   // alias path to URL
+	cached       map[string]struct{} // names of used render cache files
+	docs         []SearchDoc
+	drafts       map[string]struct{}      // file system paths of skipped drafts
+	footer       template.HTML            // rendered _footer.md of root dir
+	indexed      int                      // number of documents in docs
+	notFound     template.HTML            // rendered 404.md of root dir
+	pages        []*File                  // without readmes
+	searchIgnore ignoreList               // patterns from .searchignore
+	shared       map[string]template.HTML // rendered snippets from srv.SharedDir by file system path
+	version      hash.Hash                // hashes the path and modification time of each file
+}
+
+// index collects doc for the search index, unless srv.MaxIndexedDocs has been reached.
+func (l *loader) index(doc SearchDoc) {
+	if limit := l.srv.MaxIndexedDocs; limit > 0 && l.indexed >= limit {
+		if l.indexed == limit {
+			log.Printf("indexed %d documents, search results will be partial", limit)
+			l.indexed++ // log once
+		}
+		return
+	}
+	l.docs = append(l.docs, doc)
+	l.indexed++
+}
+
+// addAlias registers an alias path for the given URL. If the alias is already taken, the first one wins.
+func (l *loader) addAlias(alias, url string) {
+	alias = "/" + strings.Join(splitPath(alias), "/")
+	if alias == "/" {
+		return
+	}
+	if existing, ok := l.aliases[alias]; ok {
+		log.Printf("alias %s of %s is already taken by %s", alias, url, existing)
+		return
+	}
+	l.aliases[alias] = url
+}
+
+// load loads subdirs and files of dir.
+func (dir *Dir) load(l *loader) error {
+	srv := l.srv
+
 	entries, err := os.ReadDir(dir.FsPath)
 	if err != nil {
 		return err
 	}
 
+	if srv.DirAssets {
+		dir.styles, dir.scripts = slices.Clip(dir.styles), slices.Clip(dir.scripts) // don't share the backing arrays with siblings
+		for _, entry := range entries {
+			switch {
+			case !entry.Type().IsRegular():
+			case entry.Name() == "style.css":
+				dir.styles = append(dir.styles, path.Join(dir.url, entry.Name()))
+			case entry.Name() == "script.js":
+				dir.scripts = append(dir.scripts, path.Join(dir.url, entry.Name()))
+			}
+		}
+	}
+
+	var attachments = map[string]*Attachment{}
+	var customSlugs []pageFile
 	var files = map[string]*File{}
+	var filePaths = map[string]string{} // slug to file system path, for reporting collisions
 	var subdirs = map[string]*Dir{}
 	for _, entry := range entries {
 		if strings.HasPrefix(entry.Name(), ".") {
 			continue // skip hidden files
 		}
 		name := entry.Name()
-		slug := Slugify(name)
+		slug := srv.slugify(name)
 		if entry.IsDir() {
 			subdir := &Dir{
-				FsPath: filepath.Join(dir.FsPath, name),
-				Path:   append(dir.Path, dir),
-				title:  name,
-				url:    path.Join(dir.url, slug),
+				FsPath:  filepath.Join(dir.FsPath, name),
+				Path:    append(dir.Path, dir),
+				title:   srv.title(name),
+				url:     path.Join(dir.url, slug),
+				styles:  dir.styles,
+				scripts: dir.scripts,
+			}
+			if existing, ok := subdirs[slug]; ok {
+				log.Printf("slug %q of %s is already taken by %s, skipping it", slug, subdir.FsPath, existing.FsPath)
+				continue
 			}
-			if err := subdir.Load(batch); err != nil {
-				return err
+			if srv.DateDirs {
+				subdir.date = dir.date.child(name)
 			}
-			if len(subdir.Subdirs) > 0 || len(subdir.Files) > 0 {
+			if err := subdir.load(l); err != nil {
+				if err := l.skip(subdir.FsPath, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if len(subdir.EntryList) > 0 || srv.KeepEmptyDirs {
 				subdirs[slug] = subdir
 
-				doc := bluge.NewDocument(subdir.url) // _id
-				doc.AddField(bluge.NewTextField("path", subdir.PathString()).StoreValue())
-				doc.AddField(bluge.NewTextField("name", entry.Name()).SearchTermPositions().StoreValue())
-				doc.AddField(bluge.NewCompositeFieldIncluding("_all", []string{"name"}))
-				batch.Update(doc.ID(), doc)
+				doc := SearchDoc{
+					ID:      subdir.url,
+					Path:    subdir.PathString(srv.pathSeparator()),
+					Name:    entry.Name(),
+					Title:   subdir.title,
+					Section: subdir.section(),
+				}
+				if readme := subdir.Readme(); readme != nil {
+					doc.Content = readme.text() // the readme is the landing text of the dir
+				}
+				if !l.searchIgnored(subdir.FsPath, true) {
+					l.index(doc)
+				}
 			}
 			continue
 		}
-		if strings.HasSuffix(name, ".md") {
-			mdContent, err := os.ReadFile(filepath.Join(dir.FsPath, name))
+		if info, err := entry.Info(); err == nil {
+			fmt.Fprintf(l.version, "%s %d\n", path.Join(dir.url, name), info.ModTime().UnixNano())
+		}
+		if srv.DirAssets && (name == "style.css" || name == "script.js") && entry.Type().IsRegular() {
+			continue // linked into the pages, neither listed nor indexed
+		}
+		if special, ok := map[string]*template.HTML{
+			"404.md":     &l.notFound,
+			"_footer.md": &l.footer,
+		}[name]; ok && len(dir.Path) == 0 {
+			// neither listed nor indexed
+			if *special, err = l.renderSpecial(filepath.Join(dir.FsPath, name)); err != nil {
+				if err := l.skip(filepath.Join(dir.FsPath, name), err); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		isHTML := srv.ServeHTML && strings.HasSuffix(name, ".html")
+		isCSV := srv.RenderCSV && strings.HasSuffix(name, ".csv")
+		if strings.HasSuffix(name, ".md") || isHTML || isCSV {
+			fsPath := filepath.Join(dir.FsPath, name)
+			info, err := entry.Info()
 			if err != nil {
-				return err
+				if err := l.skip(fsPath, err); err != nil {
+					return err
+				}
+				continue
 			}
-			title := strings.TrimSuffix(name, ".md")
-			slug := Slugify(title)
-			file := &File{
-				title:       title,
-				HTMLContent: template.HTML(md.RenderToString(mdContent)),
-				url:         path.Join(dir.url, slug),
+			content, err := os.ReadFile(fsPath)
+			if err != nil {
+				if err := l.skip(fsPath, err); err != nil {
+					return err
+				}
+				continue
 			}
-			files[slug] = file
+			var fm frontMatter
+			switch {
+			case isCSV:
+				if content, err = renderCSV(content, name); err != nil {
+					if err := l.skip(fsPath, err); err != nil {
+						return err
+					}
+					continue
+				}
+				isHTML = true // displayed and indexed like an HTML file
+			case !isHTML:
+				fm, content, err = splitFrontMatter(content)
+				if err != nil {
+					log.Printf("error parsing front matter of %s: %v", fsPath, err)
+				}
+			}
+			if fm.Draft && !srv.IncludeDrafts {
+				l.drafts[fsPath] = struct{}{}
+				continue
+			}
+			page := pageFile{
+				name:    name,
+				fsPath:  fsPath,
+				fm:      fm,
+				content: content,
+				isHTML:  isHTML,
+				modTime: info.ModTime(),
+			}
+			if fm.Slug != "" {
+				customSlugs = append(customSlugs, page) // after the natural slugs of all siblings are taken
+				continue
+			}
+			dir.addFile(l, files, filePaths, page, srv.slugify(page.title()))
+		} else if srv.ListAttachments && entry.Type().IsRegular() {
+			if info, err := entry.Info(); err == nil {
+				attachments[name] = newAttachment(dir.url, name, info.Size())
+			}
+		}
+	}
 
-			doc := bluge.NewDocument(file.url) // _id
-			doc.AddField(bluge.NewTextField("path", dir.PathString()).StoreValue())
-			doc.AddField(bluge.NewTextField("name", entry.Name()).SearchTermPositions().StoreValue())
-			doc.AddField(bluge.NewTextField("content", string(mdContent)).SearchTermPositions().StoreValue())
-			doc.AddField(bluge.NewCompositeFieldIncluding("_all", []string{"name", "content"}))
-			batch.Update(doc.ID(), doc)
+	for _, page := range customSlugs {
+		slug := srv.slugify(page.title())
+		_, fileExists := files[page.fm.Slug]
+		_, subdirExists := subdirs[page.fm.Slug]
+		switch {
+		case page.fm.Slug != Slugify(page.fm.Slug):
+			log.Printf("invalid slug %q in %s, using %q", page.fm.Slug, page.fsPath, slug)
+		case fileExists || subdirExists:
+			log.Printf("slug %q of %s is already taken, using %q", page.fm.Slug, page.fsPath, slug)
+		default:
+			slug = page.fm.Slug
 		}
+		dir.addFile(l, files, filePaths, page, slug)
 	}
 
-	var entryList = make([]Entry, 0, len(subdirs)+len(files))
+	var entryList = make([]Entry, 0, len(subdirs)+len(files)+len(attachments))
 	for _, subdir := range subdirs {
 		entryList = append(entryList, subdir)
 	}
 	for _, file := range files {
 		entryList = append(entryList, file)
 	}
+	for _, attachment := range attachments {
+		entryList = append(entryList, attachment)
+	}
 	sort.Slice(entryList, func(i, j int) bool {
+		if srv.DateDirs {
+			return lessByDate(entryList[i], entryList[j])
+		}
 		return entryList[i].URL() < entryList[j].URL()
 	})
 
+	dir.Attachments = attachments
 	dir.Subdirs = subdirs
 	dir.Files = files
 	dir.EntryList = entryList
 	return nil
 }
 
-// without root, but with dir
-func (dir *Dir) PathString() string {
+// skip logs that the file or dir at fsPath is skipped because of err. If srv.FailFast is set, it returns err instead.
+func (l *loader) skip(fsPath string, err error) error {
+	if l.srv.FailFast {
+		return err
+	}
+	log.Printf("skipping %s: %v", fsPath, err)
+	return nil
+}
+
+// renderSpecial renders a markdown file which is displayed as part of other pages.
+func (l *loader) renderSpecial(fsPath string) (template.HTML, error) {
+	mdContent, err := os.ReadFile(fsPath)
+	if err != nil {
+		return "", err
+	}
+	_, mdContent, err = splitFrontMatter(mdContent)
+	if err != nil {
+		log.Printf("error parsing front matter of %s: %v", fsPath, err)
+	}
+	return l.render(fsPath, mdContent), nil
+}
+
+// follow follows reqpath along subdirs as far as possible. It returns the last dir and the remaining path.
+func (dir *Dir) follow(reqpath []string) (*Dir, []string) {
+	for len(reqpath) > 0 {
+		subdir, ok := dir.Subdirs[reqpath[0]]
+		if !ok {
+			break
+		}
+		dir = subdir
+		reqpath = reqpath[1:]
+	}
+	return dir, reqpath
+}
+
+// section returns the slug of the top-level dir which contains dir, or an empty string for the root dir.
+func (dir *Dir) section() string {
+	switch len(dir.Path) {
+	case 0:
+		return ""
+	case 1:
+		return path.Base(dir.url)
+	default:
+		return path.Base(dir.Path[1].url)
+	}
+}
+
+// PathTitles returns the titles of the parent dirs and of dir, without the root dir.
+func (dir *Dir) PathTitles() []string {
 	path := append(dir.Path, dir) // with dir
 	path = path[1:]               // without root
-	var sb strings.Builder
-	for _, d := range path {
-		sb.WriteString(d.title)
-		sb.WriteString(" / ")
+	var titles = make([]string, len(path))
+	for i, d := range path {
+		titles[i] = d.title
 	}
-	return sb.String()
+	return titles
+}
+
+// PathString joins the PathTitles of dir with sep.
+func (dir *Dir) PathString(sep string) string {
+	return strings.Join(dir.PathTitles(), sep)
 }
 
 func (dir *Dir) Readme() *File {
+	if dir.landing != nil {
+		return dir.landing
+	}
 	return dir.Files["readme"]
 }
 
@@ -154,12 +520,151 @@ func (dir *Dir) URL() string {
 	return dir.url
 }
 
+// pageFile is a file which has been read in order to be added as a page.
+type pageFile struct {
+	name    string
+	fsPath  string
+	fm      frontMatter
+	content []byte // without front matter
+	isHTML  bool
+	modTime time.Time
+}
+
+// title returns the file name without extension.
+func (page pageFile) title() string {
+	return strings.TrimSuffix(page.name, filepath.Ext(page.name))
+}
+
+// addFile renders the page, adds it to files with the given slug and indexes it. If another file has taken the slug, the page is skipped.
+func (dir *Dir) addFile(l *loader, files map[string]*File, filePaths map[string]string, page pageFile, slug string) {
+	srv := l.srv
+	if existing, ok := filePaths[slug]; ok {
+		log.Printf("slug %q of %s is already taken by %s, skipping it", slug, page.fsPath, existing)
+		return
+	}
+	filePaths[slug] = page.fsPath
+	file := &File{
+		title:     srv.title(page.title()),
+		canonical: page.fm.Canonical,
+		Draft:     page.fm.Draft,
+		isHTML:    page.isHTML,
+		ModTime:   page.modTime,
+		source:    page.content,
+		url:       path.Join(dir.url, slug),
+	}
+	if dir.date.parts == 3 {
+		file.Date = dir.date.time
+	}
+	body := page.content
+	if srv.TitleFromH1 && !page.isHTML {
+		if h1, rest, ok := titleHeading(page.content); ok {
+			file.title = h1
+			if srv.TitleH1Strip {
+				body = rest
+			}
+		}
+	}
+	if page.isHTML {
+		file.HTMLContent = template.HTML(body)
+	} else {
+		file.HTMLContent = l.render(page.fsPath, body)
+	}
+	if limit := srv.MaxRenderedSize; limit > 0 && len(file.HTMLContent) > limit {
+		log.Printf("rendered content of %s exceeds %d bytes, truncating it", page.fsPath, limit)
+		file.HTMLContent = truncateHTML(file.HTMLContent, limit)
+	}
+	if page.fm.Title != "" {
+		file.title = page.fm.Title
+	}
+	file.Excerpt = srv.excerpt(page.fm.Description, file.HTMLContent)
+	files[slug] = file
+	if slug != "readme" {
+		l.pages = append(l.pages, file)
+	}
+
+	for _, alias := range page.fm.Aliases {
+		l.addAlias(alias, file.url)
+	}
+
+	if slug == "readme" && srv.OmitReadmeResults && len(dir.Path) > 0 {
+		return // content is indexed with dir
+	}
+	if l.searchIgnored(page.fsPath, false) {
+		return
+	}
+
+	l.index(SearchDoc{
+		ID:      file.url,
+		Path:    dir.PathString(srv.pathSeparator()),
+		Name:    page.name,
+		Title:   file.title,
+		Content: file.text(),
+		Code:    file.code(),
+		Links:   file.links(),
+		Section: dir.section(),
+	})
+}
+
 type File struct {
 	title       string
+	canonical   string    // from front matter, empty means the URL of the file
+	Date        time.Time // from the date-structured dir which contains the file, see Server.DateDirs
+	Draft       bool
+	Excerpt     string // plain text, see Server.ExcerptSource
 	HTMLContent template.HTML
+	isHTML      bool // source is an HTML fragment, see Server.ServeHTML
+	ModTime     time.Time
+	source      []byte // markdown without front matter, or HTML
 	url         string
 }
 
+// code returns the content of code blocks for the search index.
+func (file *File) code() string {
+	if file.isHTML {
+		return ""
+	}
+	var sb strings.Builder
+	for _, token := range md.Parse(stripAuthOnly(file.source)) {
+		switch token := token.(type) {
+		case *markdown.CodeBlock:
+			sb.WriteString(token.Content)
+		case *markdown.Fence:
+			sb.WriteString(token.Content)
+		}
+	}
+	return sb.String()
+}
+
+// links returns the targets of the links in the file for the search index, separated by spaces.
+func (file *File) links() string {
+	if file.isHTML {
+		return ""
+	}
+	var hrefs []string
+	for _, token := range md.Parse(stripAuthOnly(file.source)) {
+		if inline, ok := token.(*markdown.Inline); ok {
+			for _, child := range inline.Children {
+				if link, ok := child.(*markdown.LinkOpen); ok {
+					hrefs = append(hrefs, link.Href)
+				}
+			}
+		}
+	}
+	return strings.Join(hrefs, " ")
+}
+
+// text returns the plain text content for the search index, without markdown syntax, so snippets read cleanly. Auth-only regions are removed, as search results might be anonymous.
+func (file *File) text() string {
+	if file.isHTML {
+		return htmlText(stripAuthOnly(file.source))
+	}
+	return htmlText([]byte(md.RenderToString(stripAuthOnly(file.source))))
+}
+
+func (file *File) IsAttachment() bool {
+	return false
+}
+
 func (file *File) IsDir() bool {
 	return false
 }
@@ -174,7 +679,7 @@ func (file *File) URL() string {
 
 // returns auth token for AuthHref and whether request is authenticated
 func (srv *Server) authenticated(w http.ResponseWriter, r *http.Request) (string, bool) {
-	if slices.Contains(srv.AuthTokens, "public") {
+	if slices.Contains(srv.authTokens(), "public") {
 		return "", true
 	}
 
@@ -196,17 +701,208 @@ func (srv *Server) authenticated(w http.ResponseWriter, r *http.Request) (string
 		token = queryToken
 	}
 
-	if slices.Contains(srv.AuthTokens, token) {
+	if slices.Contains(srv.authTokens(), token) {
 		return token, true
 	} else {
 		return "", false
 	}
 }
 
+// canonical returns the absolute URL of the preferred page for the file, which is given in its front matter or else its own URL, or the dir page for a readme.
+func (srv *Server) canonical(r *http.Request, file *File) string {
+	target := file.canonical
+	if target == "" {
+		target = canonicalURL(file.url)
+	}
+	if u, err := url.Parse(target); err == nil && u.IsAbs() {
+		return target
+	}
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(srv.rootURL(), target)
+	}
+	return srv.origin(r) + target
+}
+
+// liveReloadURL returns the path of HandleLiveReload if srv.DevMode is set, else an empty string.
+func (srv *Server) liveReloadURL() string {
+	if !srv.DevMode {
+		return ""
+	}
+	return path.Join(srv.rootURL(), "livereload")
+}
+
+func (srv *Server) pathSeparator() string {
+	if srv.PathSeparator == "" {
+		return " / "
+	}
+	return srv.PathSeparator
+}
+
+// logger returns srv.Logger, or the standard logger if it is nil.
+func (srv *Server) logger() *log.Logger {
+	if srv.Logger == nil {
+		return log.Default()
+	}
+	return srv.Logger
+}
+
+// liveSearchDelay returns srv.LiveSearchDelay in milliseconds.
+func (srv *Server) liveSearchDelay() int {
+	switch {
+	case srv.LiveSearchDelay < 0:
+		return -1
+	case srv.LiveSearchDelay == 0:
+		return 200
+	default:
+		return int(srv.LiveSearchDelay / time.Millisecond)
+	}
+}
+
+// rootURL returns the URL of the root dir, which is "/" or the prefix without trailing slash.
+func (srv *Server) rootURL() string {
+	if prefix := strings.Trim(srv.Prefix, "/"); prefix != "" {
+		return "/" + prefix
+	}
+	return "/"
+}
+
+// relPath returns the given URL path relative to the prefix.
+func (srv *Server) relPath(urlPath string) (string, bool) {
+	root := srv.rootURL()
+	if root == "/" {
+		return urlPath, true
+	}
+	if urlPath == root {
+		return "/", true
+	}
+	return strings.CutPrefix(urlPath, root+"/")
+}
+
+func (srv *Server) layoutData(r *http.Request, authHref, title string) layoutData {
+	anonymous := srv.anonymous(r)
+	footer := srv.current.Load().footer
+	if anonymous {
+		footer = template.HTML(stripAuthOnly([]byte(footer)))
+	}
+	return layoutData{
+		Anonymous:       anonymous,
+		AuthHref:        authHref,
+		ContainsAuthKey: r.URL.Query().Has("auth"),
+		Footer:          footer,
+		Lang:            requestLang(r),
+		LiveReload:      srv.liveReloadURL(),
+		LiveSearchDelay: srv.liveSearchDelay(),
+		NoIndex:         srv.NoIndex,
+		OpenSearch:      path.Join(srv.rootURL(), "opensearch.xml"),
+		PathSeparator:   srv.pathSeparator(),
+		RootURL:         strings.TrimSuffix(srv.rootURL(), "/") + "/",
+		SearchAPI:       path.Join(srv.rootURL(), "search"),
+		SiteTitle:       srv.RootTitle,
+		Title:           title,
+	}
+}
+
+// scopeData returns the search scope data for the layout.
+func (srv *Server) scopeData(dir *Dir) *scopeData {
+	relpath, _ := srv.relPath(dir.url)
+	return &scopeData{
+		Path:  relpath,
+		Title: dir.title,
+	}
+}
+
+// serveError renders the error template with the given status code. For status 404, the content of 404.md is displayed instead of the message, if present.
+func (srv *Server) serveError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	st := srv.current.Load()
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var content template.HTML
+	if status == http.StatusNotFound {
+		content = st.notFound
+		if srv.anonymous(r) {
+			content = template.HTML(stripAuthOnly([]byte(content)))
+		}
+	}
+	lang := requestLang(r)
+	w.WriteHeader(status)
+	if err := st.tmpl.error.Execute(w, errorData{
+		layoutData: srv.layoutData(r, "", translate(lang, http.StatusText(status))),
+		Content:    content,
+		Message:    translate(lang, message),
+		RequestID:  requestID(r),
+		Status:     status,
+		StatusText: translate(lang, http.StatusText(status)),
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// serveLogin renders a form where the user can enter an auth token.
+func (srv *Server) serveLogin(w http.ResponseWriter, r *http.Request) {
+	var hidden []hiddenInput
+	for name, values := range r.URL.Query() {
+		if name == "auth" {
+			continue
+		}
+		for _, value := range values {
+			hidden = append(hidden, hiddenInput{name, value})
+		}
+	}
+	sort.SliceStable(hidden, func(i, j int) bool {
+		return hidden[i].Name < hidden[j].Name
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := srv.current.Load().tmpl.login.Execute(w, loginData{
+		layoutData: srv.layoutData(r, "", translate(requestLang(r), http.StatusText(http.StatusUnauthorized))),
+		Action:     r.URL.Path,
+		Hidden:     hidden,
+	}); err != nil {
+		log.Println(err)
+	}
+}
+
+// methodGet replies with 405 if the request method is neither GET nor HEAD.
+func methodGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+// noIndex asks search engines not to index the response if srv.NoIndex is set.
+func (srv *Server) noIndex(w http.ResponseWriter) {
+	if srv.NoIndex {
+		w.Header().Set("X-Robots-Tag", "noindex")
+	}
+}
+
+// uriLengthOK replies with 414 if the request URI exceeds srv.MaxURLLength.
+func (srv *Server) uriLengthOK(w http.ResponseWriter, r *http.Request) bool {
+	if srv.MaxURLLength > 0 && len(r.URL.RequestURI()) > srv.MaxURLLength {
+		http.Error(w, "request URI too long", http.StatusRequestURITooLong)
+		return false
+	}
+	return true
+}
+
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	srv.noIndex(w)
+	if !srv.loaded(w, true) {
+		return
+	}
+	st := srv.current.Load()
+	w.Header().Set("X-Content-Version", st.version)
+	w.Header().Add("Vary", "Accept-Language")
+	if !methodGet(w, r) || !srv.uriLengthOK(w, r) {
+		return
+	}
+
 	token, authenticated := srv.authenticated(w, r)
 	if !authenticated {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		srv.serveLogin(w, r)
 		return
 	}
 
@@ -225,22 +921,18 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// request path
-	reqpath := strings.FieldsFunc(r.URL.Path, func(r rune) bool { return r == '/' })
+	relpath, ok := srv.relPath(r.URL.Path)
+	if !ok {
+		srv.serveError(w, r, http.StatusNotFound, "The requested page does not exist.")
+		return
+	}
+	reqpath := splitPath(relpath)
 	if len(reqpath) > 16 {
-		http.Error(w, "path too long", http.StatusUnprocessableEntity)
+		srv.serveError(w, r, http.StatusUnprocessableEntity, "The requested path is too long.")
 		return
 	}
 
-	// follow dirs
-	var dir = srv.Root
-	for len(reqpath) > 0 {
-		newdir, ok := dir.Subdirs[reqpath[0]]
-		if !ok {
-			break
-		}
-		dir = newdir
-		reqpath = reqpath[1:]
-	}
+	dir, reqpath := st.root.follow(reqpath)
 
 	var base string
 	if dir.url != "" && !strings.HasSuffix(dir.url, "/") {
@@ -249,188 +941,460 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// serve dir
 	if len(reqpath) == 0 {
-		if err := dirTmpl.Execute(w, dirData{
-			layoutData: layoutData{
-				AuthHref:        authHref,
-				Base:            base,
-				ContainsAuthKey: r.URL.Query().Has("auth"),
-				Title:           dir.title,
-			},
-			Dir: dir,
+		if r.URL.Query().Get("download") == "zip" {
+			srv.serveZip(w, dir, srv.anonymous(r))
+			return
+		}
+
+		var tmpl = st.tmpl.dir
+		var recent []*File
+		if dir == st.root {
+			tmpl = st.tmpl.index
+			recent = st.recent
+		}
+		layout := srv.layoutData(r, authHref, dir.title)
+		layout.Base = base
+		layout.Current = dir.url
+		layout.Styles = dir.styles
+		layout.Scripts = dir.scripts
+		layout.Breadcrumbs = srv.breadcrumbs(dir.Path)
+		layout.Sidebar = srv.sidebar(dir.url)
+		if dir != st.root {
+			layout.Scope = srv.scopeData(dir)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		entries, pagination := srv.paginate(r, srv.entries(dir))
+		var showAll string
+		if pagination == nil {
+			entries, showAll = srv.limitEntries(r, entries)
+		}
+		groups, letters := srv.letterGroups(entries)
+		if err := tmpl.Execute(w, dirData{
+			layoutData: layout,
+			Dir:        dir,
+			Entries:    entries,
+			Groups:     groups,
+			Letters:    letters,
+			Pagination: pagination,
+			Recent:     recent,
+			ShowAll:    showAll,
 		}); err != nil {
 			log.Println(err)
 		}
 		return
 	}
 
-	// serve markdown file
-	if file, ok := dir.Files[reqpath[0]]; ok {
-		if err := fileTmpl.Execute(w, fileData{
-			layoutData: layoutData{
-				AuthHref:        authHref,
-				Base:            base,
-				ContainsAuthKey: r.URL.Query().Has("auth"),
-				Title:           file.title,
-			},
-			Dir:  dir,
-			File: file,
+	// serve markdown file, but not for attachments in a folder with the same name, like "deploy/diagram.png" referenced from "deploy.md"
+	if file, ok := dir.Files[reqpath[0]]; ok && len(reqpath) == 1 {
+		if r.URL.Query().Get("source") == "1" && !file.isHTML {
+			source := file.source
+			if srv.anonymous(r) {
+				source = stripAuthOnly(source)
+			}
+			layout := srv.layoutData(r, authHref, file.title+" ("+translate(requestLang(r), "Source")+")")
+			layout.Base = base
+			layout.Breadcrumbs = srv.breadcrumbs(slices.Concat(dir.Path, []*Dir{dir}))
+			layout.Current = file.url
+			layout.Sidebar = srv.sidebar(file.url)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := st.tmpl.source.Execute(w, sourceData{
+				layoutData: layout,
+				File:       file,
+				Source:     highlightMarkdown(source),
+			}); err != nil {
+				log.Println(err)
+			}
+			return
+		}
+
+		w.Header().Add("Vary", "Accept")
+		offers := []string{"text/html", "text/markdown", "application/json"}
+		if file.isHTML {
+			offers = []string{"text/html", "application/json"}
+		}
+		switch negotiate(r, offers...) {
+		case "text/markdown":
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			if srv.anonymous(r) {
+				w.Write(stripAuthOnly(file.source))
+			} else {
+				w.Write(file.source)
+			}
+			return
+		case "application/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			json.NewEncoder(w).Encode(struct {
+				Title string        `json:"title"`
+				HTML  template.HTML `json:"html"`
+			}{
+				Title: file.title,
+				HTML:  srv.layoutData(r, authHref, file.title).HTML(file),
+			})
+			return
+		}
+
+		layout := srv.layoutData(r, authHref, file.title)
+		layout.Base = base
+		layout.Breadcrumbs = srv.breadcrumbs(slices.Concat(dir.Path, []*Dir{dir}))
+		layout.Canonical = srv.canonical(r, file)
+		layout.Current = file.url
+		layout.Styles = dir.styles
+		layout.Scripts = dir.scripts
+		layout.Sidebar = srv.sidebar(file.url)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := st.tmpl.file.Execute(w, fileData{
+			layoutData: layout,
+			Dir:        dir,
+			File:       file,
 		}); err != nil {
 			log.Println(err)
 		}
 		return
 	}
 
+	// redirect alias
+	if url, ok := st.aliases["/"+strings.Join(splitPath(relpath), "/")]; ok {
+		if r.URL.RawQuery != "" {
+			url += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, url, http.StatusMovedPermanently)
+		return
+	}
+
 	// serve other file
-	http.ServeFile(w, r, filepath.Join(dir.FsPath, filepath.Join(reqpath...)))
+	for _, segment := range reqpath {
+		if strings.HasPrefix(segment, ".") {
+			srv.serveError(w, r, http.StatusNotFound, "The requested page does not exist.") // don't serve hidden files
+			return
+		}
+	}
+	fsPath := filepath.Join(dir.FsPath, filepath.Join(reqpath...))
+	if _, ok := st.drafts[fsPath]; ok {
+		srv.serveError(w, r, http.StatusNotFound, "The requested page does not exist.")
+		return
+	}
+	info, err := os.Stat(fsPath)
+	if err != nil || info.IsDir() {
+		if err == nil {
+			// dir without pages, subdirs or listed attachments, which has been skipped by Dir.load, see Server.KeepEmptyDirs
+			srv.serveError(w, r, http.StatusNotFound, "The requested folder contains no pages.")
+			return
+		}
+		if srv.FoldAttachmentCase {
+			if name, ok := foldedName(fsPath); ok {
+				if _, isDraft := st.drafts[filepath.Join(filepath.Dir(fsPath), name)]; !isDraft {
+					u := url.URL{Path: path.Join(path.Dir(r.URL.Path), name), RawQuery: r.URL.RawQuery}
+					http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+					return
+				}
+			}
+		}
+		srv.serveError(w, r, http.StatusNotFound, "The requested page does not exist.")
+		return
+	}
+	if srv.anonymous(r) && srv.withAuthOnly(fsPath) {
+		// raw page source, like "page.md" or "_footer.md"
+		content, err := os.ReadFile(fsPath)
+		if err != nil {
+			srv.serveError(w, r, http.StatusNotFound, "The requested page does not exist.")
+			return
+		}
+		http.ServeContent(w, r, filepath.Base(fsPath), info.ModTime(), bytes.NewReader(stripAuthOnly(content)))
+		return
+	}
+	if srv.NegotiateImages {
+		fsPath = negotiateImage(w, r, fsPath)
+	}
+	http.ServeFile(w, r, fsPath)
 }
 
-func (srv *Server) handleSearchHTML(w http.ResponseWriter, r *http.Request, authHref, search string) {
-	search = strings.TrimSpace(search)
-	matches, err := srv.search(search)
-	if err != nil {
-		return
+// negotiateImage returns the path of an AVIF or WebP sibling of the given PNG, JPEG or GIF image if the client accepts it, otherwise fsPath.
+func negotiateImage(w http.ResponseWriter, r *http.Request, fsPath string) string {
+	ext := filepath.Ext(fsPath)
+	switch strings.ToLower(ext) {
+	case ".gif", ".jpeg", ".jpg", ".png":
+	default:
+		return fsPath
 	}
-	err = searchTmpl.Execute(w, searchData{
-		layoutData: layoutData{
-			AuthHref:        authHref,
-			ContainsAuthKey: r.URL.Query().Has("auth"),
-			Search:          search,
-			Title:           "Search: " + search,
-		},
-		Matches:   matches,
-		RootTitle: srv.RootTitle,
-	})
-	if err != nil {
-		log.Println(err)
+	w.Header().Add("Vary", "Accept")
+	for _, alt := range []struct {
+		ext      string
+		mimeType string
+	}{
+		{".avif", "image/avif"},
+		{".webp", "image/webp"},
+	} {
+		if !accepts(r, alt.mimeType) {
+			continue
+		}
+		altPath := strings.TrimSuffix(fsPath, ext) + alt.ext
+		if info, err := os.Stat(altPath); err == nil && !info.IsDir() {
+			return altPath
+		}
 	}
+	return fsPath
 }
 
-func (srv *Server) HandleSearchAPI(w http.ResponseWriter, r *http.Request) {
+type dirEntry struct {
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	IsDir      bool   `json:"isDir"`
+	Attachment bool   `json:"attachment,omitempty"` // served as is, not as a page
+	Size       int64  `json:"size,omitempty"`       // of attachments
+}
+
+type dirListing struct {
+	Title   string     `json:"title"`
+	Path    string     `json:"path"`
+	Entries []dirEntry `json:"entries"`
+}
+
+// HandleDirAPI returns the entries of a single dir, given by the "path" query parameter.
+func (srv *Server) HandleDirAPI(w http.ResponseWriter, r *http.Request) {
+	srv.noIndex(w)
+	if !methodGet(w, r) || !srv.uriLengthOK(w, r) || !srv.loaded(w, false) {
+		return
+	}
+
 	_, authenticated := srv.authenticated(w, r)
 	if !authenticated {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	input := r.URL.Query().Get("s")
-	result, err := srv.search(input)
-	if err != nil {
+	reqpath := splitPath(r.URL.Query().Get("path"))
+	if len(reqpath) > 16 {
+		http.Error(w, "path too long", http.StatusUnprocessableEntity)
+		return
+	}
+	dir, reqpath := srv.Root().follow(reqpath)
+	if len(reqpath) > 0 {
+		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
-	json.NewEncoder(w).Encode(result)
-}
 
-type DocumentMatch struct {
-	Href    template.URL  `json:"href"`
-	Path    string        `json:"path"` // without name
-	Name    template.HTML `json:"name"`
-	Content template.HTML `json:"content"` // empty for dirs
+	var listing = dirListing{
+		Title:   dir.title,
+		Path:    dir.url,
+		Entries: make([]dirEntry, 0, len(dir.EntryList)),
+	}
+	for _, entry := range srv.entries(dir) {
+		e := dirEntry{
+			Title: entry.Title(),
+			URL:   entry.URL(),
+			IsDir: entry.IsDir(),
+		}
+		if attachment, ok := entry.(*Attachment); ok {
+			e.Attachment = true
+			e.Size = attachment.Size
+		}
+		listing.Entries = append(listing.Entries, e)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(listing)
 }
 
-func (srv *Server) search(input string) ([]DocumentMatch, error) {
-	// crop input, lowercase (required for bluge.PrefixQuery and bluge.WildcardQuery, which don't have an analyzer), limit to four words, remove too long words and duplicates
-	if len(input) > 128 {
-		input = input[:128]
+func (srv *Server) Reload() error {
+	srv.reloadMu.Lock()
+	defer srv.reloadMu.Unlock()
+
+	tmpl, err := srv.parseTemplates()
+	if err != nil {
+		return err
 	}
-	input = strings.ToLower(input)
-	words := strings.Fields(input)
-	if len(words) > 4 {
-		words = words[:4]
+
+	authTokens := slices.Clone(srv.AuthTokens)
+	if srv.AuthTokenFile != "" {
+		fileTokens, err := readTokenFile(srv.AuthTokenFile)
+		if err != nil {
+			return err
+		}
+		authTokens = append(authTokens, fileTokens...)
 	}
-	var wordMap = make(map[string]any)
-	for _, word := range words {
-		if len(word) <= 32 {
-			wordMap[word] = struct{}{}
+
+	for ext, typ := range srv.MIMETypes {
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		if err := mime.AddExtensionType(ext, typ); err != nil {
+			return fmt.Errorf("adding MIME type of %s: %w", ext, err)
 		}
 	}
 
-	query := bluge.NewBooleanQuery()
-	for word := range wordMap {
-		wordQuery := bluge.NewBooleanQuery()
-		wordQuery.AddShould(bluge.NewFuzzyQuery(word).SetField("_all").SetFuzziness(1))
-		wordQuery.AddShould(bluge.NewPrefixQuery(word).SetField("_all"))
-		wordQuery.AddShould(bluge.NewWildcardQuery("*" + word + "*").SetField("_all"))
-		query.AddMust(wordQuery)
+	for _, field := range srv.SearchFields {
+		if !slices.Contains(storedFields, field) {
+			return fmt.Errorf("unknown search field %q, expected %s", field, strings.Join(storedFields, ", "))
+		}
 	}
-	request := bluge.NewTopNSearch(10, query).IncludeLocations()
 
-	highlighter := highlight.NewHTMLHighlighter()
+	if srv.Searcher == nil {
+		srv.Searcher = NewBlugeSearcher()
+	}
 
-	dmi, err := srv.Reader.Search(context.Background(), request)
-	if err != nil {
-		return nil, err
-	}
-	var matches []DocumentMatch
-	for next, err := dmi.Next(); err == nil && next != nil; next, err = dmi.Next() {
-		var match DocumentMatch
-		err = next.VisitStoredFields(func(field string, value []byte) bool {
-			switch field {
-			case "_id":
-				match.Href = template.URL(value)
-			case "path":
-				match.Path = string(value)
-			case "name":
-				match.Name = template.HTML(value)
-				if locations, ok := next.Locations[field]; ok {
-					if fragment := highlighter.BestFragment(locations, value); len(fragment) > 0 {
-						match.Name = template.HTML(fragment)
-					}
-				}
-			case "content":
-				if locations, ok := next.Locations[field]; ok {
-					if fragment := highlighter.BestFragment(locations, value); len(fragment) > 0 {
-						match.Content = template.HTML(fragment)
-					}
-				}
-			}
-			return true
-		})
-		if err != nil {
-			return nil, err
+	if srv.CacheDir != "" {
+		if err := os.MkdirAll(srv.CacheDir, 0o755); err != nil {
+			return err
 		}
-
-		matches = append(matches, match)
 	}
-	if err != nil {
-		return nil, err
+	fsDir := srv.FsDir
+	if srv.GitRef != "" {
+		fsDir, err = snapshotGitRef(srv.FsDir, srv.GitRef)
+		if err != nil {
+			return err
+		}
 	}
 
-	return matches, nil
-}
-
-func (srv *Server) Reload() error {
-	// update root and search index
-	indexWriter, err := bluge.OpenWriter(bluge.InMemoryOnlyConfig())
+	searchIgnore, err := readIgnoreList(filepath.Join(fsDir, searchIgnoreFile))
 	if err != nil {
+		if srv.GitRef != "" {
+			os.RemoveAll(fsDir)
+		}
 		return err
 	}
-	batch := bluge.NewBatch()
+
+	l := &loader{
+		srv:          srv,
+		fsDir:        fsDir,
+		aliases:      make(map[string]string),
+		cached:       make(map[string]struct{}),
+		drafts:       make(map[string]struct{}),
+		searchIgnore: searchIgnore,
+		shared:       make(map[string]template.HTML),
+		version:      sha256.New(),
+	}
 
 	root := &Dir{
-		FsPath: srv.FsDir,
+		FsPath: fsDir,
 		title:  srv.RootTitle,
-		url:    "/",
+		url:    srv.rootURL(),
 	}
-	err = root.Load(batch)
-	if err != nil {
-		panic(err)
-	}
-	if err := indexWriter.Batch(batch); err != nil {
+	if err := root.load(l); err != nil {
+		if srv.GitRef != "" {
+			os.RemoveAll(fsDir)
+		}
 		return err
 	}
+	l.pruneCache()
+	var sharedPaths []string // shared snippets are outside of the content folder, so hash their content
+	for fsPath := range l.shared {
+		sharedPaths = append(sharedPaths, fsPath)
+	}
+	sort.Strings(sharedPaths)
+	for _, fsPath := range sharedPaths {
+		fmt.Fprintf(l.version, "%s %s\n", fsPath, l.shared[fsPath])
+	}
+	if srv.RootLandingFile != "" {
+		if landing, ok := root.Files[srv.slugify(strings.TrimSuffix(srv.RootLandingFile, ".md"))]; ok {
+			root.landing = landing
+		} else {
+			log.Printf("root landing file %s not found", srv.RootLandingFile)
+		}
+	}
+	gen := srv.indexGen.Add(1)
+	if !srv.BackgroundIndex {
+		if err := srv.rebuildIndex(gen, l.docs); err != nil {
+			if srv.GitRef != "" {
+				os.RemoveAll(fsDir)
+			}
+			return err
+		}
+	}
 
-	srv.Root = root
-	srv.Reader, _ = indexWriter.Reader() // reader is a snapshot
+	sort.SliceStable(l.pages, func(i, j int) bool {
+		return l.pages[i].ModTime.After(l.pages[j].ModTime)
+	})
+	var recent []*File
+	if srv.ShowRecent > 0 {
+		recent = l.pages[:min(srv.ShowRecent, len(l.pages))]
+	}
+
+	st := &state{
+		root:       root,
+		aliases:    l.aliases,
+		authTokens: authTokens,
+		drafts:     l.drafts,
+		footer:     l.footer,
+		notFound:   l.notFound,
+		pages:      l.pages,
+		recent:     recent,
+		tmpl:       tmpl,
+		version:    hex.EncodeToString(l.version.Sum(nil)[:8]),
+	}
+	if srv.GitRef != "" {
+		st.snapshot = fsDir
+	}
+	if old := srv.current.Swap(st); old != nil && old.snapshot != "" {
+		time.AfterFunc(gracePeriod, func() {
+			os.RemoveAll(old.snapshot) // requests which are still served from the old snapshot might fail
+		})
+	}
+	srv.broadcastReload()
+	if srv.BackgroundIndex {
+		go func() {
+			if err := srv.rebuildIndex(gen, l.docs); err != nil {
+				log.Printf("error rebuilding search index: %v", err)
+			}
+		}()
+	}
 	return nil
 }
 
-// replaces diacritic and accent characters with the underlying character
-var transformer = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+// rebuildIndex replaces the search index with docs. If a later Reload has started meanwhile, it does nothing, so consecutive background rebuilds are coalesced.
+func (srv *Server) rebuildIndex(gen uint64, docs []SearchDoc) error {
+	srv.indexMu.Lock()
+	defer srv.indexMu.Unlock()
+	if gen != srv.indexGen.Load() {
+		return nil // superseded
+	}
+	for _, doc := range docs {
+		srv.Searcher.Index(doc)
+	}
+	return srv.Searcher.Reload()
+}
+
+// splitPath splits a slash-separated path into its non-empty segments.
+func splitPath(p string) []string {
+	return strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+}
+
+// removeDiacritics returns a transformer which replaces diacritic and accent characters with the underlying character. A chain is not safe for concurrent use, so each call returns a new one.
+func removeDiacritics() transform.Transformer {
+	return transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+}
+
+// title returns the display title for the given file or folder name (without extension).
+func (srv *Server) title(name string) string {
+	if srv.HumanizeTitles {
+		if humanized := Humanize(name); humanized != "" {
+			return humanized
+		}
+	}
+	return name
+}
+
+// Humanize replaces dashes and underscores with spaces and upper-cases the first letter of each word.
+func Humanize(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	})
+	for i, word := range words {
+		r, size := utf8.DecodeRuneInString(word)
+		words[i] = string(unicode.ToUpper(r)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+func (srv *Server) slugify(s string) string {
+	if srv.Transliterate {
+		return SlugifyTransliterated(s)
+	}
+	return Slugify(s)
+}
 
 // Slugify returns a modified version of the given string in lower case, with [a-z0-9] retained and a dash in each gap.
 func Slugify(s string) string {
 	s = strings.TrimSpace(s)
-	s, _, _ = transform.String(transformer, s)
+	s, _, _ = transform.String(removeDiacritics(), s)
 	s = strings.ToLower(s)
 	strs := strings.FieldsFunc(s, func(r rune) bool {
 		if 'a' <= r && r <= 'z' {
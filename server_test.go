@@ -0,0 +1,792 @@
+package markdump
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeTree creates the given files, by slash-separated path, in a temporary folder and returns it.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		fsPath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fsPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fsPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// newTestServer loads a public server with the given files. If configure is not nil, it is called before the first Reload.
+func newTestServer(t *testing.T, files map[string]string, configure func(*Server)) *Server {
+	t.Helper()
+	srv := &Server{
+		AuthTokens: []string{"public"},
+		FsDir:      writeTree(t, files),
+		RootTitle:  "Home",
+	}
+	if configure != nil {
+		configure(srv)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// serve sends a GET request for target to handler and returns the recorded response. Headers are given as name-value pairs.
+func serve(handler http.Handler, target string, headers ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestHumanizeTitles(t *testing.T) {
+	files := map[string]string{
+		"getting_started.md":  "Hello",
+		"user-guide/intro.md": "---\ntitle: intro_page\n---\nHello",
+	}
+	for _, test := range []struct {
+		humanize  bool
+		fileTitle string
+		dirTitle  string
+	}{
+		{false, "getting_started", "user-guide"},
+		{true, "Getting Started", "User Guide"},
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.HumanizeTitles = test.humanize
+		})
+		root := srv.Root()
+		file, ok := root.Files["getting-started"]
+		if !ok {
+			t.Fatalf("humanize %t: file not found by slug", test.humanize)
+		}
+		if file.Title() != test.fileTitle {
+			t.Errorf("humanize %t: got file title %q, want %q", test.humanize, file.Title(), test.fileTitle)
+		}
+		if dir := root.Subdirs["user-guide"]; dir.Title() != test.dirTitle {
+			t.Errorf("humanize %t: got dir title %q, want %q", test.humanize, dir.Title(), test.dirTitle)
+		}
+		if title := root.Subdirs["user-guide"].Files["intro"].Title(); title != "intro_page" {
+			t.Errorf("humanize %t: got front matter title %q, want it unchanged", test.humanize, title)
+		}
+	}
+}
+
+func TestDirAPI(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"ops/deploy/checklist.md": "Hello",
+		"ops/deploy/hosts/web.md": "Hello",
+		"ops/readme.md":           "Hello",
+	}, nil)
+	handler := http.HandlerFunc(srv.HandleDirAPI)
+
+	w := serve(handler, "/api/dir?path=/ops/deploy")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	var listing dirListing
+	if err := json.Unmarshal(w.Body.Bytes(), &listing); err != nil {
+		t.Fatal(err)
+	}
+	want := dirListing{
+		Title: "deploy",
+		Path:  "/ops/deploy",
+		Entries: []dirEntry{
+			{Title: "checklist", URL: "/ops/deploy/checklist"},
+			{Title: "hosts", URL: "/ops/deploy/hosts", IsDir: true},
+		},
+	}
+	if !reflect.DeepEqual(listing, want) {
+		t.Fatalf("got %+v, want %+v", listing, want)
+	}
+
+	if w := serve(handler, "/api/dir?path=/ops/missing"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown dir: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestErrorPages(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+	})
+
+	w := serve(srv, "/")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "<title>Unauthorized</title>") {
+		t.Fatalf("401 page is not rendered within the layout: %s", body)
+	}
+
+	w = serve(srv, "/"+strings.Repeat("a/", 17)+"?auth=secret")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "<title>Unprocessable Entity</title>") || !strings.Contains(body, "The requested path is too long.") {
+		t.Fatalf("422 page is not rendered from the error template: %s", body)
+	}
+}
+
+func TestLoginPage(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/page.md": "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+	})
+
+	w := serve(srv, "/docs/page?tab=2")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`action="/docs/page"`,
+		`<input type="hidden" name="tab" value="2">`,
+		`name="auth"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("login page does not contain %s", want)
+		}
+	}
+
+	w = serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=hello")
+	if w.Code != http.StatusUnauthorized || strings.Contains(w.Body.String(), "<form") {
+		t.Fatalf("search API: got status %d and body %q, want a plain 401", w.Code, w.Body.String())
+	}
+}
+
+func TestRootLandingFile(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md":  "Readme text",
+		"welcome.md": "Welcome text",
+	}, func(srv *Server) {
+		srv.RootLandingFile = "welcome.md"
+	})
+	body := serve(srv, "/").Body.String()
+	if !strings.Contains(body, "Welcome text") || strings.Contains(body, "Readme text") {
+		t.Fatalf("root page does not display the landing file instead of the readme: %s", body)
+	}
+}
+
+func TestAliasRedirect(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"guide/page.md": "---\naliases: [/old/path]\n---\nHello",
+		"a.md":          "---\naliases: [/taken]\n---\nFirst",
+		"b.md":          "---\naliases: [/taken]\n---\nSecond",
+	}, nil)
+	for _, test := range []struct {
+		target   string
+		location string
+	}{
+		{"/old/path?x=1", "/guide/page?x=1"},
+		{"/taken", "/a"}, // first one wins
+	} {
+		w := serve(srv, test.target)
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != test.location {
+			t.Errorf("GET %s: got status %d and location %q, want a redirect to %s", test.target, w.Code, w.Header().Get("Location"), test.location)
+		}
+	}
+}
+
+// setModTimes sets the modification times of the given files in the content folder of srv and reloads it.
+func setModTimes(t *testing.T, srv *Server, modTimes map[string]time.Time) {
+	t.Helper()
+	for name, modTime := range modTimes {
+		if err := os.Chtimes(filepath.Join(srv.FsDir, filepath.FromSlash(name)), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShowRecent(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"old.md":        "Old",
+		"newest.md":     "Newest",
+		"sub/middle.md": "Middle",
+	}, func(srv *Server) {
+		srv.ShowRecent = 2
+	})
+	now := time.Now()
+	setModTimes(t, srv, map[string]time.Time{
+		"old.md":        now.Add(-3 * time.Hour),
+		"newest.md":     now.Add(-1 * time.Hour),
+		"sub/middle.md": now.Add(-2 * time.Hour),
+	})
+	var urls []string
+	for _, file := range srv.current.Load().recent {
+		urls = append(urls, file.URL())
+	}
+	if want := []string{"/newest", "/sub/middle"}; !slices.Equal(urls, want) {
+		t.Fatalf("got %v, want %v", urls, want)
+	}
+}
+
+func TestMultipleRoots(t *testing.T) {
+	internal := newTestServer(t, map[string]string{
+		"secret-plan.md": "The internal walrus.",
+	}, func(srv *Server) {
+		srv.Prefix = "/internal/"
+	})
+	public := newTestServer(t, map[string]string{
+		"announcement.md": "The public pelican.",
+	}, func(srv *Server) {
+		srv.Prefix = "/public/"
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/internal/", internal)
+	mux.Handle("/public/", public)
+
+	for _, test := range []struct {
+		target string
+		status int
+	}{
+		{"/internal/secret-plan", http.StatusOK},
+		{"/public/announcement", http.StatusOK},
+		{"/internal/announcement", http.StatusNotFound},
+		{"/public/secret-plan", http.StatusNotFound},
+	} {
+		if w := serve(mux, test.target); w.Code != test.status {
+			t.Errorf("GET %s: got status %d, want %d", test.target, w.Code, test.status)
+		}
+	}
+
+	if hrefs := searchHrefs(t, internal, "walrus"); !slices.Equal(hrefs, []string{"/internal/secret-plan"}) {
+		t.Errorf("internal search: got %v", hrefs)
+	}
+	if hrefs := searchHrefs(t, public, "walrus"); len(hrefs) > 0 {
+		t.Errorf("public search: got %v, want no matches from the other root", hrefs)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "Hello",
+	}, nil)
+	for _, handler := range []http.Handler{
+		srv,
+		http.HandlerFunc(srv.HandleSearchAPI),
+		http.HandlerFunc(srv.HandleDirAPI),
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("got status %d, want %d", w.Code, http.StatusMethodNotAllowed)
+		}
+		if allow := w.Header().Get("Allow"); allow != "GET, HEAD" {
+			t.Errorf("got Allow header %q", allow)
+		}
+	}
+}
+
+func TestMaxURLLength(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "Hello",
+	}, func(srv *Server) {
+		srv.MaxURLLength = 64
+	})
+	if w := serve(srv, "/?q="+strings.Repeat("x", 32)); w.Code != http.StatusOK {
+		t.Fatalf("short URL: got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := serve(srv, "/?q="+strings.Repeat("x", 64)); w.Code != http.StatusRequestURITooLong {
+		t.Fatalf("long URL: got status %d, want %d", w.Code, http.StatusRequestURITooLong)
+	}
+	if w := serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s="+strings.Repeat("x", 64)); w.Code != http.StatusRequestURITooLong {
+		t.Fatalf("long search API URL: got status %d, want %d", w.Code, http.StatusRequestURITooLong)
+	}
+}
+
+func TestNegotiateImages(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md": "![Foo](foo.png)",
+		"foo.png":   "png data",
+		"foo.webp":  "webp data",
+	}, func(srv *Server) {
+		srv.NegotiateImages = true
+	})
+	for _, test := range []struct {
+		accept string
+		body   string
+	}{
+		{"image/webp,image/*", "webp data"},
+		{"image/png", "png data"},
+	} {
+		w := serve(srv, "/foo.png", "Accept", test.accept)
+		if body := w.Body.String(); body != test.body {
+			t.Errorf("Accept %s: got %q, want %q", test.accept, body, test.body)
+		}
+		if vary := w.Header().Values("Vary"); !slices.Contains(vary, "Accept") {
+			t.Errorf("Accept %s: got Vary %v, want Accept", test.accept, vary)
+		}
+	}
+}
+
+func TestCollapseSingleChild(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"projects/2024/alpha/plan.md": "Hello",
+		"other.md":                    "Hello",
+	}, func(srv *Server) {
+		srv.CollapseSingleChild = true
+	})
+	entries := srv.entries(srv.Root())
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if title, url := entries[1].Title(), entries[1].URL(); title != "projects / 2024 / alpha" || url != "/projects/2024/alpha" {
+		t.Fatalf("got %q linking to %s, want the collapsed chain", title, url)
+	}
+	if body := serve(srv, "/").Body.String(); !strings.Contains(body, "projects / 2024 / alpha") {
+		t.Fatal("root page does not display the collapsed chain")
+	}
+}
+
+func TestContentTypes(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/page.md": "Hello",
+		"notes.txt":    "Plain",
+	}, nil)
+	for _, test := range []struct {
+		handler     http.Handler
+		target      string
+		contentType string
+	}{
+		{srv, "/", "text/html; charset=utf-8"},
+		{srv, "/docs", "text/html; charset=utf-8"},
+		{srv, "/docs/page", "text/html; charset=utf-8"},
+		{srv, "/docs/page?source=1", "text/html; charset=utf-8"},
+		{srv, "/?s=hello", "text/html; charset=utf-8"},
+		{srv, "/missing", "text/html; charset=utf-8"},
+		{srv, "/notes.txt", "text/plain; charset=utf-8"},
+		{http.HandlerFunc(srv.HandleSearchAPI), "/search?s=hello", "application/json; charset=utf-8"},
+		{http.HandlerFunc(srv.HandleDirAPI), "/api/dir?path=/docs", "application/json; charset=utf-8"},
+	} {
+		if contentType := serve(test.handler, test.target).Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("GET %s: got Content-Type %q, want %q", test.target, contentType, test.contentType)
+		}
+	}
+}
+
+func TestNotFoundPage(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"404.md":  "Nothing *here*, try the search.",
+		"page.md": "Hello",
+	}, nil)
+	w := serve(srv, "/missing/page")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "Nothing <em>here</em>, try the search.") {
+		t.Fatalf("404.md is not displayed: %s", body)
+	}
+	if _, ok := srv.Root().Files["404"]; ok {
+		t.Fatal("404.md is listed as a page")
+	}
+}
+
+func TestServeHTML(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"report.html": `<h2>Quarterly <b>report</b></h2><p>Revenue of the <span class="x">platypus</span> division.</p>`,
+	}, func(srv *Server) {
+		srv.ServeHTML = true
+	})
+	body := serve(srv, "/report").Body.String()
+	if !strings.Contains(body, "<title>report</title>") || !strings.Contains(body, `<span class="x">platypus</span>`) {
+		t.Fatalf("HTML file is not displayed within the layout: %s", body)
+	}
+	if hrefs := searchHrefs(t, srv, "platypus"); !slices.Equal(hrefs, []string{"/report"}) {
+		t.Fatalf("got %v, want the HTML file", hrefs)
+	}
+	if hrefs := searchHrefs(t, srv, "span"); len(hrefs) > 0 {
+		t.Fatalf("markup is indexed: %v", hrefs)
+	}
+}
+
+func TestNoIndex(t *testing.T) {
+	files := map[string]string{
+		"page.md": "Hello",
+	}
+	const meta = `<meta name="robots" content="noindex">`
+	for _, noIndex := range []bool{false, true} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.NoIndex = noIndex
+		})
+		for _, target := range []string{"/", "/page", "/missing"} {
+			w := serve(srv, target)
+			if header := w.Header().Get("X-Robots-Tag") == "noindex"; header != noIndex {
+				t.Errorf("NoIndex %t, GET %s: got header %t", noIndex, target, header)
+			}
+			if hasMeta := strings.Contains(w.Body.String(), meta); hasMeta != noIndex {
+				t.Errorf("NoIndex %t, GET %s: got meta tag %t", noIndex, target, hasMeta)
+			}
+		}
+		w := serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=hello")
+		if header := w.Header().Get("X-Robots-Tag") == "noindex"; header != noIndex {
+			t.Errorf("NoIndex %t, search API: got header %t", noIndex, header)
+		}
+	}
+}
+
+func TestFooter(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"_footer.md":   "Maintained by the *docs team*.",
+		"docs/page.md": "Hello",
+	}, nil)
+	for _, target := range []string{"/", "/docs", "/docs/page"} {
+		if body := serve(srv, target).Body.String(); !strings.Contains(body, "Maintained by the <em>docs team</em>.") {
+			t.Errorf("GET %s: footer is missing", target)
+		}
+	}
+	if _, ok := srv.Root().Files["footer"]; ok {
+		t.Error("_footer.md is listed as a page")
+	}
+}
+
+func TestFooterAuthOnly(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"_footer.md": "Maintained by the docs team.\n\n<!-- auth-only -->\nCall 555-0100.\n<!-- /auth-only -->",
+		"page.md":    "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"staff"}
+	})
+	for _, test := range []struct {
+		target   string
+		status   int
+		internal bool
+	}{
+		{"/page", http.StatusUnauthorized, false},
+		{"/page?auth=wrong", http.StatusUnauthorized, false},
+		{"/page?auth=staff", http.StatusOK, true},
+		{"/missing?auth=staff", http.StatusNotFound, true},
+	} {
+		w := serve(srv, test.target)
+		if w.Code != test.status {
+			t.Errorf("GET %s: got status %d, want %d", test.target, w.Code, test.status)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "Maintained by the docs team.") {
+			t.Errorf("GET %s: footer is missing", test.target)
+		}
+		if internal := strings.Contains(body, "555-0100"); internal != test.internal {
+			t.Errorf("GET %s: got auth-only footer %t, want %t", test.target, internal, test.internal)
+		}
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	files := map[string]string{
+		"draft.md":     "---\ndraft: true\n---\nThe unfinished capybara.",
+		"published.md": "Hello",
+	}
+	for _, includeDrafts := range []bool{false, true} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.IncludeDrafts = includeDrafts
+		})
+		wantStatus := http.StatusNotFound
+		if includeDrafts {
+			wantStatus = http.StatusOK
+		}
+		for _, target := range []string{"/draft", "/draft.md"} {
+			if w := serve(srv, target); w.Code != wantStatus {
+				t.Errorf("IncludeDrafts %t, GET %s: got status %d, want %d", includeDrafts, target, w.Code, wantStatus)
+			}
+		}
+		if listed := strings.Contains(serve(srv, "/").Body.String(), `href="/draft"`); listed != includeDrafts {
+			t.Errorf("IncludeDrafts %t: got listed %t", includeDrafts, listed)
+		}
+		if found := len(searchHrefs(t, srv, "capybara")) > 0; found != includeDrafts {
+			t.Errorf("IncludeDrafts %t: got searchable %t", includeDrafts, found)
+		}
+	}
+}
+
+func TestCustomSlug(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"Meeting Notes 2024-01.md": "---\nslug: kickoff\n---\nThe kickoff armadillo.",
+		"a.md":                     "---\nslug: b\n---\nCustom slug of a.",
+		"b.md":                     "Natural slug of b.",
+		"c.md":                     "---\nslug: guide\n---\nCustom slug of c.",
+		"guide/page.md":            "Hello",
+		"x.md":                     "---\nslug: short\n---\nFirst custom slug.",
+		"y.md":                     "---\nslug: short\n---\nSecond custom slug.",
+	}, nil)
+	for _, test := range []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/kickoff", http.StatusOK, "The kickoff armadillo."},
+		{"/meeting-notes-2024-01", http.StatusNotFound, ""},
+		{"/b", http.StatusOK, "Natural slug of b."}, // a natural slug wins over a custom one
+		{"/a", http.StatusOK, "Custom slug of a."},  // fallback
+		{"/guide", http.StatusOK, "page"},           // subdirs win as well, even if they are loaded after the file
+		{"/c", http.StatusOK, "Custom slug of c."},
+		{"/short", http.StatusOK, "First custom slug."},
+		{"/y", http.StatusOK, "Second custom slug."},
+	} {
+		w := serve(srv, test.target)
+		if w.Code != test.status || !strings.Contains(w.Body.String(), test.body) {
+			t.Errorf("GET %s: got status %d, want %d and %q", test.target, w.Code, test.status, test.body)
+		}
+	}
+	if hrefs := searchHrefs(t, srv, "armadillo"); !slices.Equal(hrefs, []string{"/kickoff"}) {
+		t.Errorf("got %v, want the custom slug", hrefs)
+	}
+}
+
+func TestUnreadableFile(t *testing.T) {
+	files := map[string]string{
+		"a.md": "First",
+		"c.md": "Third",
+	}
+	unreadable := func(srv *Server) {
+		// a dangling symlink can't be read, even by root
+		if err := os.Symlink(filepath.Join(srv.FsDir, "missing"), filepath.Join(srv.FsDir, "b.md")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := newTestServer(t, files, unreadable)
+	for _, slug := range []string{"a", "c"} {
+		if _, ok := srv.Root().Files[slug]; !ok {
+			t.Errorf("readable file %s is missing", slug)
+		}
+	}
+	if _, ok := srv.Root().Files["b"]; ok {
+		t.Error("unreadable file is loaded")
+	}
+
+	srv = &Server{
+		AuthTokens: []string{"public"},
+		FailFast:   true,
+		FsDir:      writeTree(t, files),
+	}
+	unreadable(srv)
+	if err := srv.Reload(); err == nil {
+		t.Error("FailFast: got no error")
+	}
+}
+
+func TestRelativeAttachments(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"ops/deploy.md":                     "![Diagram](images/diagram.png)",
+		"ops/images/diagram.png":            "diagram",
+		"ops/infra/net/hosts.md":            "![Map](assets/maps/map.png)",
+		"ops/infra/net/assets/maps/map.png": "map",
+		"ops/deploy/step.png":               "step", // folder with the same name as a file
+	}, nil)
+	for _, test := range []struct {
+		target string
+		body   string
+	}{
+		{"/ops/images/diagram.png", "diagram"},
+		{"/ops/infra/net/assets/maps/map.png", "map"},
+		{"/ops/deploy/step.png", "step"},
+	} {
+		w := serve(srv, test.target)
+		if w.Code != http.StatusOK || w.Body.String() != test.body {
+			t.Errorf("GET %s: got status %d and %q, want %q", test.target, w.Code, w.Body.String(), test.body)
+		}
+	}
+	if body := serve(srv, "/ops/deploy").Body.String(); !strings.Contains(body, `<base href="/ops/">`) {
+		t.Errorf("relative URLs of the page are not resolved against its dir: %s", body)
+	}
+}
+
+func TestContentVersion(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, nil)
+	version := serve(srv, "/page").Header().Get("X-Content-Version")
+	if version == "" {
+		t.Fatal("header is missing")
+	}
+
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := serve(srv, "/").Header().Get("X-Content-Version"); got != version {
+		t.Fatalf("unchanged content: got version %s, want %s", got, version)
+	}
+
+	setModTimes(t, srv, map[string]time.Time{
+		"page.md": time.Now().Add(time.Hour),
+	})
+	if got := serve(srv, "/page").Header().Get("X-Content-Version"); got == version {
+		t.Fatal("modified content: version has not changed")
+	}
+}
+
+// TestConcurrentReload is meant to be run with the race detector: go test -race
+func TestConcurrentReload(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/page.md": "The concurrent lemur.",
+		"readme.md":    "Hello",
+	}, nil)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, target := range []string{"/", "/docs", "/docs/page", "/?s=lemur"} {
+					if w := serve(srv, target); w.Code != http.StatusOK {
+						t.Errorf("GET %s: got status %d", target, w.Code)
+						return
+					}
+				}
+				serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=lemur")
+				serve(http.HandlerFunc(srv.HandleDirAPI), "/api/dir?path=/docs")
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := srv.Reload(); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestMIMETypes(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"app.mjs":           "export default 1;",
+		"data.markdumptest": "custom",
+	}, func(srv *Server) {
+		srv.MIMETypes = map[string]string{
+			".mjs":         "text/javascript",
+			"markdumptest": "application/x-markdump-test", // without leading dot
+		}
+	})
+	for _, test := range []struct {
+		target      string
+		contentType string
+	}{
+		{"/app.mjs", "text/javascript"},
+		{"/data.markdumptest", "application/x-markdump-test"},
+	} {
+		if contentType := serve(srv, test.target).Header().Get("Content-Type"); !strings.HasPrefix(contentType, test.contentType) {
+			t.Errorf("GET %s: got Content-Type %q, want %q", test.target, contentType, test.contentType)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"guide/page.md":   "---\naliases: [/old/path]\n---\nHello",
+		"guide/readme.md": "Guide",
+		"copy.md":         "---\ncanonical: /guide/page\n---\nHello",
+	}, func(srv *Server) {
+		srv.BaseURL = "https://wiki.example.com/"
+	})
+	for _, test := range []struct {
+		target    string
+		canonical string
+	}{
+		{"/guide/page?x=1", "https://wiki.example.com/guide/page"},
+		{"/copy", "https://wiki.example.com/guide/page"},
+		{"/guide/readme", "https://wiki.example.com/guide"},
+	} {
+		body := serve(srv, test.target).Body.String()
+		if want := `<link rel="canonical" href="` + test.canonical + `">`; !strings.Contains(body, want) {
+			t.Errorf("GET %s: does not contain %s", test.target, want)
+		}
+	}
+}
+
+func TestPathString(t *testing.T) {
+	files := map[string]string{
+		"ops/deploy/run.md": "Run the tapir.",
+	}
+	srv := newTestServer(t, files, nil)
+	root := srv.Root()
+	deploy := root.Subdirs["ops"].Subdirs["deploy"]
+	if got := deploy.PathString(" / "); got != "ops / deploy" {
+		t.Errorf("got %q, want no trailing separator", got)
+	}
+	if got := root.PathString(" / "); got != "" {
+		t.Errorf("root: got %q, want an empty string", got)
+	}
+	if got := deploy.PathTitles(); !slices.Equal(got, []string{"ops", "deploy"}) {
+		t.Errorf("got titles %v", got)
+	}
+
+	for _, test := range []struct {
+		separator string
+		want      string
+	}{
+		{"", "ops / deploy"},
+		{" › ", "ops › deploy"},
+	} {
+		srv := newTestServer(t, files, func(srv *Server) {
+			srv.PathSeparator = test.separator
+		})
+		matches, err := srv.search(context.Background(), searchParams{input: "tapir", fields: storedFields})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) != 1 || matches[0].Path != test.want {
+			t.Errorf("separator %q: got %+v, want path %q", test.separator, matches, test.want)
+		}
+	}
+}
+
+func TestPrunedDir(t *testing.T) {
+	files := map[string]string{
+		"page.md":               "Hello",
+		"empty/.gitkeep":        "",
+		"empty/deeper/.gitkeep": "",
+	}
+	srv := newTestServer(t, files, nil)
+	for _, target := range []string{"/empty", "/empty/", "/empty/deeper"} {
+		w := serve(srv, target)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET %s: got status %d, want %d", target, w.Code, http.StatusNotFound)
+		}
+		if body := w.Body.String(); !strings.Contains(body, "The requested folder contains no pages.") || !strings.Contains(body, "<html") {
+			t.Errorf("GET %s: got %s, want the templated error page", target, body)
+		}
+	}
+	if code := serve(srv, "/empty/missing.png").Code; code != http.StatusNotFound {
+		t.Errorf("missing file in a pruned dir: got status %d", code)
+	}
+
+	srv = newTestServer(t, files, func(srv *Server) {
+		srv.KeepEmptyDirs = true
+	})
+	if code := serve(srv, "/empty").Code; code != http.StatusOK {
+		t.Errorf("KeepEmptyDirs: got status %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,38 @@
+package markdump
+
+import "strings"
+
+// navNode is an entry in the sidebar navigation tree.
+type navNode struct {
+	Entry
+	Active   bool // current page
+	Open     bool // contains current page
+	Children []navNode
+}
+
+// sidebar returns the navigation tree up to srv.SidebarDepth levels. Dirs along the current URL are always expanded.
+func (srv *Server) sidebar(current string) []navNode {
+	root := srv.Root()
+	if srv.SidebarDepth <= 0 || root == nil {
+		return nil
+	}
+	return navTree(root, current, srv.SidebarDepth)
+}
+
+func navTree(dir *Dir, current string, depth int) []navNode {
+	var nodes = make([]navNode, 0, len(dir.EntryList))
+	for _, entry := range dir.EntryList {
+		node := navNode{
+			Entry:  entry,
+			Active: entry.URL() == current,
+		}
+		if subdir, ok := entry.(*Dir); ok {
+			node.Open = strings.HasPrefix(current, subdir.url+"/")
+			if depth > 1 || node.Open || node.Active {
+				node.Children = navTree(subdir, current, depth-1)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
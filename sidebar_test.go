@@ -0,0 +1,35 @@
+package markdump
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSidebar(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"about.md":          "Hello",
+		"guide/intro.md":    "Hello",
+		"guide/deep/one.md": "Hello",
+		"ops/deploy.md":     "Hello",
+	}, func(srv *Server) {
+		srv.SidebarDepth = 1
+	})
+	nodes := srv.sidebar("/guide/intro")
+	var urls []string
+	for _, node := range nodes {
+		urls = append(urls, node.URL())
+	}
+	if want := []string{"/about", "/guide", "/ops"}; !slices.Equal(urls, want) {
+		t.Fatalf("got top-level entries %v, want %v", urls, want)
+	}
+	guide, ops := nodes[1], nodes[2]
+	if !guide.Open || len(guide.Children) != 2 || !guide.Children[1].Active {
+		t.Errorf("dir of the current page is not expanded: %+v", guide)
+	}
+	if ops.Children != nil {
+		t.Errorf("other dir is expanded beyond the depth: %+v", ops)
+	}
+	if deep := guide.Children[0]; deep.Children != nil {
+		t.Errorf("subdir beyond the depth is expanded: %+v", deep)
+	}
+}
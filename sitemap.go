@@ -0,0 +1,66 @@
+package markdump
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"time"
+)
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// HandleSitemap serves a sitemap of all dirs and files. It does not require authentication, so it replies 404 unless the server is public.
+func (srv *Server) HandleSitemap(w http.ResponseWriter, r *http.Request) {
+	if !methodGet(w, r) || !srv.loaded(w, false) {
+		return
+	}
+	if !slices.Contains(srv.authTokens(), "public") {
+		http.NotFound(w, r)
+		return
+	}
+	urls := srv.sitemapURLs(r, srv.Root(), nil)
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Loc < urls[j].Loc
+	})
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(sitemapURLSet{URLs: urls})
+}
+
+// sitemapURLs appends the absolute URLs of dir and all files and subdirs in it to dst. Files whose canonical page is another one, like readme files, are skipped.
+func (srv *Server) sitemapURLs(r *http.Request, dir *Dir, dst []sitemapURL) []sitemapURL {
+	origin := srv.origin(r)
+	dst = append(dst, sitemapURL{Loc: origin + dir.url})
+	for _, file := range dir.Files {
+		if loc := srv.canonical(r, file); loc == origin+file.url {
+			dst = append(dst, sitemapURL{Loc: loc, LastMod: file.ModTime.UTC().Format(time.DateOnly)})
+		}
+	}
+	for _, subdir := range dir.Subdirs {
+		dst = srv.sitemapURLs(r, subdir, dst)
+	}
+	return dst
+}
+
+// sitemapLink returns the absolute URL of the sitemap for robots.txt, or an empty string if srv.BaseURL is not set or the sitemap is not served.
+func (srv *Server) sitemapLink() string {
+	if !slices.Contains(srv.authTokens(), "public") {
+		return ""
+	}
+	u, err := url.Parse(srv.BaseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host + path.Join(srv.rootURL(), "sitemap.xml")
+}
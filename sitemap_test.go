@@ -0,0 +1,55 @@
+package markdump
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSitemap(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"readme.md":        "Hello",
+		"about.md":         "About",
+		"old.md":           "---\ncanonical: /about\n---\nOld",
+		"guide/install.md": "Install",
+	}, func(srv *Server) {
+		srv.BaseURL = "https://wiki.example.com/"
+	})
+	setModTimes(t, srv, map[string]time.Time{
+		"about.md":         time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"guide/install.md": time.Date(2024, 5, 20, 8, 0, 0, 0, time.UTC),
+	})
+
+	w := serve(http.HandlerFunc(srv.HandleSitemap), "/sitemap.xml")
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/xml; charset=utf-8" {
+		t.Fatalf("got Content-Type %q", contentType)
+	}
+	var urlset sitemapURLSet
+	if err := xml.Unmarshal(w.Body.Bytes(), &urlset); err != nil {
+		t.Fatal(err)
+	}
+	want := []sitemapURL{
+		{Loc: "https://wiki.example.com/"},
+		{Loc: "https://wiki.example.com/about", LastMod: "2024-03-01"},
+		{Loc: "https://wiki.example.com/guide"},
+		{Loc: "https://wiki.example.com/guide/install", LastMod: "2024-05-20"},
+	}
+	if len(urlset.URLs) != len(want) {
+		t.Fatalf("got %v, want %v", urlset.URLs, want)
+	}
+	for i := range want {
+		if urlset.URLs[i] != want[i] {
+			t.Errorf("got %v, want %v", urlset.URLs[i], want[i])
+		}
+	}
+
+	private := newTestServer(t, map[string]string{
+		"page.md": "Hello",
+	}, func(srv *Server) {
+		srv.AuthTokens = []string{"secret"}
+	})
+	if w := serve(http.HandlerFunc(private.HandleSitemap), "/sitemap.xml"); w.Code != http.StatusNotFound {
+		t.Errorf("private server: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,14 @@
+// reloads the page when the server has reloaded the content, see Server.DevMode
+(function() {
+	let url = document.currentScript.dataset.url;
+	function connect() {
+		let ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + url);
+		ws.onmessage = () => location.reload();
+		ws.onclose = evt => {
+			if(!evt.wasClean) {
+				setTimeout(connect, 1000); // server restarts
+			}
+		};
+	}
+	connect();
+})();
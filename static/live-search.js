@@ -1,7 +1,21 @@
 // reuse xhr, so livesearch() can abort it
 var xhr = new XMLHttpRequest();
 
+// timer of the debounced search
+var livesearchTimer = null;
+
+// livesearch queries the search API after the input has not changed for the delay given in the data-delay attribute of the search input
 function livesearch() {
+	let searchInput = document.getElementById("search");
+	let delay = parseInt(searchInput.dataset.delay ?? "0", 10);
+	if(delay < 0) {
+		return // live search is disabled
+	}
+	clearTimeout(livesearchTimer);
+	livesearchTimer = setTimeout(livesearchNow, delay);
+}
+
+function livesearchNow() {
 	// clear live search results
 	let resultDiv = document.getElementById("live-search-result");
 	resultDiv.innerHTML = "";
@@ -13,7 +27,8 @@ function livesearch() {
 	}
 
 	// check input
-	let input = document.getElementById("search").value.trim();
+	let searchInput = document.getElementById("search");
+	let input = searchInput.value.trim();
 	if(input.length < 1) {
 		// restore regular results
 		if(formSearchResult) {
@@ -26,22 +41,34 @@ function livesearch() {
 	xhr.abort();
 	xhr.onreadystatechange = function() {
 		if (this.readyState == 4 && this.status == 200) {
-			resultDiv.insertAdjacentHTML("beforeend", `<h1>Search Results</h1>`);
+			resultDiv.insertAdjacentHTML("beforeend", `<h1>${resultDiv.dataset.heading}</h1>`);
 			let result = JSON.parse(xhr.response);
 			if(result != null && result.length > 0) {
+				if(result[0].loose) {
+					resultDiv.insertAdjacentHTML("beforeend", `<p>${resultDiv.dataset.loose}</p>`);
+				}
 				let dl = resultDiv.insertAdjacentElement("beforeend", document.createElement("dl"));
 				for(const match of result) {
-					dl.insertAdjacentHTML("beforeend", `<dt><a href="${match.href}"><strong>${match.path}${match.name}</strong></a></dt>`);
+					dl.insertAdjacentHTML("beforeend", `<dt><a href="${match.href}"><strong>${match.path ? match.path + resultDiv.dataset.separator : ""}${match.name ?? ""}</strong></a></dt>`);
 					if(match.content) {
 						dl.insertAdjacentHTML("beforeend", `<dd>${match.content}</dd>`);
 					}
 				}
 			} else {
-				resultDiv.insertAdjacentHTML("beforeend", "<p>No search results.</p>");
+				resultDiv.insertAdjacentHTML("beforeend", `<p>${resultDiv.dataset.empty}</p>`);
 			}
 		}
 	};
-	xhr.open("GET", "/search?s=" + encodeURIComponent(input));
+	let url = searchInput.dataset.api + "?s=" + encodeURIComponent(input);
+	let searchIn = document.getElementById("search-in");
+	if(searchIn && searchIn.checked) {
+		url += "&in=" + encodeURIComponent(searchIn.value);
+	}
+	let auth = new URLSearchParams(window.location.search).get("auth");
+	if(auth) {
+		url += "&auth=" + encodeURIComponent(auth); // the auth cookie might not have been stored, e.g. without https
+	}
+	xhr.open("GET", url);
 	xhr.send(null);
 }
 
@@ -1,6 +1,66 @@
 package static
 
-import "embed"
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
 
 //go:embed *
 var Files embed.FS
+
+var (
+	fingerprinted = map[string]string{} // name to fingerprinted name
+	original      = map[string]string{} // fingerprinted name to name
+)
+
+func init() {
+	err := fs.WalkDir(Files, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || strings.HasSuffix(name, ".go") {
+			return err
+		}
+		content, err := fs.ReadFile(Files, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		ext := path.Ext(name)
+		fp := strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		fingerprinted[name] = fp
+		original[fp] = name
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Path returns the URL path of the fingerprinted file, e.g. "/static/style.1a2b3c4d.css" for "style.css". Unknown names are returned unfingerprinted.
+func Path(name string) string {
+	if fp, ok := fingerprinted[name]; ok {
+		return "/static/" + fp
+	}
+	return "/static/" + name
+}
+
+// Handler serves Files. It expects the "/static/" prefix to be stripped. Fingerprinted files are cached forever.
+func Handler() http.Handler {
+	fileServer := http.FileServer(http.FS(Files))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := original[strings.TrimPrefix(r.URL.Path, "/")]; ok {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = "/" + name
+			r = r2
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,39 @@
+package static
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	fp := Path("style.css")
+	if !regexp.MustCompile(`^/static/style\.[0-9a-f]{8}\.css$`).MatchString(fp) {
+		t.Fatalf("got %s, want a fingerprinted path", fp)
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(fp, "/static"), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	content, err := fs.ReadFile(Files, "style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != string(content) {
+		t.Fatal("fingerprinted path does not resolve to the file")
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); !strings.Contains(cacheControl, "immutable") {
+		t.Fatalf("got Cache-Control %q, want immutable", cacheControl)
+	}
+
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/style.css", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != "" {
+		t.Fatalf("unfingerprinted path: got status %d and Cache-Control %q", w.Code, w.Header().Get("Cache-Control"))
+	}
+}
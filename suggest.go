@@ -0,0 +1,77 @@
+package markdump
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// HandleSuggest returns the documents whose titles match the "s" query parameter as you type, as a JSON array of suggestions. The search backend must implement Suggester.
+func (srv *Server) HandleSuggest(w http.ResponseWriter, r *http.Request) {
+	srv.noIndex(w)
+	if !methodGet(w, r) || !srv.uriLengthOK(w, r) || !srv.loaded(w, false) {
+		return
+	}
+
+	_, authenticated := srv.authenticated(w, r)
+	if !authenticated {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	suggester, ok := srv.Searcher.(Suggester)
+	if !ok {
+		http.Error(w, "suggestions not supported", http.StatusNotImplemented)
+		return
+	}
+
+	var prefixes []string
+	for _, word := range strings.Fields(strings.ToLower(r.URL.Query().Get("s"))) {
+		if len(word) <= 32 && !slices.Contains(prefixes, word) {
+			prefixes = append(prefixes, word)
+		}
+	}
+	if len(prefixes) > 4 {
+		prefixes = prefixes[:4]
+	}
+
+	var suggestions = []Suggestion{} // encode "no suggestions" as empty array, not null
+	if len(prefixes) > 0 {
+		ctx, cancel := srv.searchContext(r)
+		defer cancel()
+		err := suggester.Suggest(ctx, prefixes, func(suggestion Suggestion) error {
+			suggestions = append(suggestions, suggestion)
+			return nil
+		})
+		switch {
+		case errors.Is(err, context.Canceled):
+			return // client has gone
+		case errors.Is(err, context.DeadlineExceeded):
+			http.Error(w, "suggestions timed out", http.StatusGatewayTimeout)
+			return
+		case err != nil:
+			log.Printf("error suggesting %q: %v", prefixes, err)
+			http.Error(w, "suggestions failed", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(suggestions)
+}
+
+// titleMatches reports whether each prefix is the prefix of a word in the lowercase title.
+func titleMatches(title string, prefixes []string) bool {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	})
+	for _, prefix := range prefixes {
+		if !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, prefix) }) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package markdump
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"deployment-guide.md":               "Hello",
+		"design-notes.md":                   "Hello",
+		"release-plan.md":                   "Hello",
+		"internationalization-checklist.md": "Hello",
+	}, nil)
+	for _, test := range []struct {
+		input string
+		want  []string
+	}{
+		{"de", []string{"/deployment-guide", "/design-notes"}},
+		{"gu", []string{"/deployment-guide"}},
+		{"de no", []string{"/design-notes"}},
+		{"internationaliz", []string{"/internationalization-checklist"}}, // longer than the indexed prefixes
+		{"internationalix", nil},
+		{"x", nil},
+	} {
+		var suggestions []Suggestion
+		if err := json.Unmarshal(serve(http.HandlerFunc(srv.HandleSuggest), "/suggest?s="+url.QueryEscape(test.input)).Body.Bytes(), &suggestions); err != nil {
+			t.Fatal(err)
+		}
+		var hrefs []string
+		for _, suggestion := range suggestions {
+			hrefs = append(hrefs, suggestion.Href)
+		}
+		slices.Sort(hrefs)
+		if !slices.Equal(hrefs, test.want) {
+			t.Errorf("%q: got %v, want %v", test.input, hrefs, test.want)
+		}
+	}
+}
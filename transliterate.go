@@ -0,0 +1,64 @@
+package markdump
+
+import "strings"
+
+// transliterator replaces lower case characters which would otherwise be removed or mangled by Slugify.
+var transliterator = strings.NewReplacer(
+	// German
+	"ä", "ae",
+	"ö", "oe",
+	"ü", "ue",
+	"ß", "ss",
+	// other Latin
+	"æ", "ae",
+	"œ", "oe",
+	"ø", "o",
+	"å", "aa",
+	"ł", "l",
+	"đ", "d",
+	"ð", "d",
+	"þ", "th",
+	// Cyrillic
+	"а", "a",
+	"б", "b",
+	"в", "v",
+	"г", "g",
+	"д", "d",
+	"е", "e",
+	"ё", "yo",
+	"ж", "zh",
+	"з", "z",
+	"и", "i",
+	"й", "y",
+	"к", "k",
+	"л", "l",
+	"м", "m",
+	"н", "n",
+	"о", "o",
+	"п", "p",
+	"р", "r",
+	"с", "s",
+	"т", "t",
+	"у", "u",
+	"ф", "f",
+	"х", "kh",
+	"ц", "ts",
+	"ч", "ch",
+	"ш", "sh",
+	"щ", "shch",
+	"ъ", "",
+	"ы", "y",
+	"ь", "",
+	"э", "e",
+	"ю", "yu",
+	"я", "ya",
+	"є", "ye",
+	"і", "i",
+	"ї", "yi",
+	"ґ", "g",
+)
+
+// SlugifyTransliterated is like Slugify, but transliterates German umlauts, some other Latin characters and Cyrillic characters first.
+func SlugifyTransliterated(s string) string {
+	return Slugify(transliterator.Replace(strings.ToLower(s)))
+}
@@ -0,0 +1,35 @@
+package markdump
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSlugifyTransliterated(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"Größe und Übermaß", "groesse-und-uebermass"},
+		{"Привет, мир", "privet-mir"},
+		{"Щука и ёж", "shchuka-i-yozh"},
+	} {
+		if got := SlugifyTransliterated(test.input); got != test.want {
+			t.Errorf("SlugifyTransliterated(%q) = %q, want %q", test.input, got, test.want)
+		}
+	}
+}
+
+func TestTransliteratedFileNames(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"Größe.md":  "German",
+		"Привет.md": "Russian",
+	}, func(srv *Server) {
+		srv.Transliterate = true
+	})
+	for _, target := range []string{"/groesse", "/privet"} {
+		if w := serve(srv, target); w.Code != http.StatusOK {
+			t.Errorf("GET %s: got status %d, want %d", target, w.Code, http.StatusOK)
+		}
+	}
+}
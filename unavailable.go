@@ -0,0 +1,37 @@
+package markdump
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// unavailableTmpl is used instead of the error template, which is not available before Reload has succeeded.
+var unavailableTmpl = template.Must(template.New("unavailable").Parse(`<!doctype html>
+<html lang="en">
+	<head>
+		<meta charset="utf-8">
+		<meta name="viewport" content="width=device-width, initial-scale=1">
+		<title>Service Unavailable</title>
+	</head>
+	<body>
+		<h1>Service Unavailable</h1>
+		<p>The content is being maintained. Please try again later.</p>
+	</body>
+</html>
+`))
+
+// loaded replies with 503 and returns false if no Reload has succeeded yet. If html is set, it renders a maintenance page, else a plain text message.
+func (srv *Server) loaded(w http.ResponseWriter, html bool) bool {
+	if srv.current.Load() != nil {
+		return true
+	}
+	w.Header().Set("Retry-After", "60")
+	if !html {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	unavailableTmpl.Execute(w, nil)
+	return false
+}
@@ -0,0 +1,39 @@
+package markdump
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnavailable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "content")
+	srv := &Server{AuthTokens: []string{"public"}, FsDir: dir, RootTitle: "Home"}
+	if err := srv.Reload(); err == nil {
+		t.Fatal("reload of a missing directory succeeded")
+	}
+
+	w := serve(srv, "/")
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("page: got status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(w.Body.String(), "<h1>Service Unavailable</h1>") {
+		t.Fatalf("page: got %s, want the maintenance page", w.Body.String())
+	}
+	w = serve(http.HandlerFunc(srv.HandleSearchAPI), "/search?s=x")
+	if w.Code != http.StatusServiceUnavailable || strings.Contains(w.Body.String(), "<html") {
+		t.Fatalf("search API: got status %d, body %q", w.Code, w.Body.String())
+	}
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if code := serve(srv, "/").Code; code != http.StatusOK {
+		t.Fatalf("after a successful reload: got status %d", code)
+	}
+}
@@ -0,0 +1,91 @@
+package markdump
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// serveZip streams the source files of dir as a zip archive. Hidden files, drafts and symlinks are skipped. If anonymous is true, auth-only regions are removed from markdown files.
+func (srv *Server) serveZip(w http.ResponseWriter, dir *Dir, anonymous bool) {
+	name := path.Base(dir.url)
+	if name == "/" || name == "." {
+		name = Slugify(srv.RootTitle)
+	}
+	if name == "" {
+		name = "markdump"
+	}
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, name))
+
+	drafts := srv.current.Load().drafts
+	zw := zip.NewWriter(w)
+	err := filepath.WalkDir(dir.FsPath, func(fsPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if fsPath != dir.FsPath && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil // dirs are created implicitly, symlinks are skipped
+		}
+		if _, ok := drafts[fsPath]; ok {
+			return nil
+		}
+		rel, err := filepath.Rel(dir.FsPath, fsPath)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(name, filepath.ToSlash(rel))
+		header.Method = zip.Deflate
+		if anonymous && srv.withAuthOnly(fsPath) {
+			content, err := os.ReadFile(fsPath)
+			if err != nil {
+				return err
+			}
+			fw, err := zw.CreateHeader(header)
+			if err != nil {
+				return err
+			}
+			_, err = fw.Write(stripAuthOnly(content))
+			return err
+		}
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(fsPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		log.Printf("error creating zip of %s: %v", dir.FsPath, err)
+		panic(http.ErrAbortHandler) // don't send an incomplete archive
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("error creating zip of %s: %v", dir.FsPath, err)
+	}
+}
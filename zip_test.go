@@ -0,0 +1,51 @@
+package markdump
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestServeZip(t *testing.T) {
+	srv := newTestServer(t, map[string]string{
+		"docs/readme.md":     "Hello",
+		"docs/img/logo.png":  "png data",
+		"docs/.hidden/x.md":  "Hidden",
+		"docs/draft.md":      "---\ndraft: true\n---\nDraft",
+		"other/unrelated.md": "Other",
+	}, nil)
+	w := serve(srv, "/docs?download=zip")
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/zip" {
+		t.Fatalf("got Content-Type %q", contentType)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents = map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = string(content)
+	}
+	want := map[string]string{
+		"docs/readme.md":    "Hello",
+		"docs/img/logo.png": "png data",
+	}
+	if len(contents) != len(want) {
+		t.Fatalf("got %v, want %v", contents, want)
+	}
+	for name, content := range want {
+		if contents[name] != content {
+			t.Errorf("got %q for %s, want %q", contents[name], name, content)
+		}
+	}
+}